@@ -10,6 +10,7 @@ import (
 	"github.com/lmittmann/tint"
 	"github.com/vancomm/minesweeper-server/internal/config"
 	"github.com/vancomm/minesweeper-server/internal/database"
+	"github.com/vancomm/minesweeper-server/internal/repository"
 )
 
 //go:embed migrations/*.sql
@@ -26,7 +27,7 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
-	_, migrator, err := database.ConnectAndMigrate(ctx, migrations)
+	db, migrator, err := database.ConnectAndMigrate(ctx, migrations)
 	if err != nil {
 		logger.Error("failed to connect to db", slog.Any("error", err))
 		os.Exit(1)
@@ -37,5 +38,16 @@ func main() {
 	} else {
 		logger.Info("migration successful", slog.Uint64("version", uint64(version)), slog.Bool("dirty", dirty))
 	}
+
+	/*
+	 * Game states are saved in a format the database can't
+	 * convert, so bring any older ones up to date here.
+	 */
+	rewritten, err := repository.New(db).RewriteGameStates(ctx)
+	if err != nil {
+		logger.Error("failed to rewrite game states", slog.Int("rewritten", rewritten), slog.Any("error", err))
+		os.Exit(1)
+	}
+	logger.Info("game states up to date", slog.Int("rewritten", rewritten))
 	os.Exit(0)
 }
@@ -0,0 +1,2 @@
+ALTER TABLE game_session
+	ADD COLUMN used_undo boolean NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+ALTER TABLE game_session
+	ADD COLUMN custom_layout boolean NOT NULL DEFAULT false;
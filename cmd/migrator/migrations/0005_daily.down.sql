@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS game_session_daily_idx;
+
+ALTER TABLE game_session
+	DROP COLUMN daily_date,
+	DROP COLUMN daily_preset;
@@ -0,0 +1,7 @@
+ALTER TABLE game_session
+	ADD COLUMN daily_date date NULL,
+	ADD COLUMN daily_preset text NULL;
+
+CREATE INDEX IF NOT EXISTS game_session_daily_idx
+	ON game_session (daily_date, daily_preset, player_id, started_at)
+	WHERE daily_date IS NOT NULL;
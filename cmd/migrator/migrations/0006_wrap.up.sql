@@ -0,0 +1,2 @@
+ALTER TABLE game_session
+	ADD COLUMN wrap boolean NOT NULL DEFAULT false;
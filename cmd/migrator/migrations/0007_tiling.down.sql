@@ -0,0 +1,2 @@
+ALTER TABLE game_session
+	DROP COLUMN tiling;
@@ -0,0 +1,2 @@
+ALTER TABLE game_session
+	ADD COLUMN tiling text NOT NULL DEFAULT 'square';
@@ -0,0 +1,2 @@
+ALTER TABLE game_session
+	ADD COLUMN mines_per_cell integer NOT NULL DEFAULT 1;
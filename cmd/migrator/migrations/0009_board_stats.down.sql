@@ -0,0 +1,4 @@
+ALTER TABLE game_session
+	DROP COLUMN bbbv,
+	DROP COLUMN openings,
+	DROP COLUMN islands;
@@ -0,0 +1,4 @@
+ALTER TABLE game_session
+	ADD COLUMN bbbv integer NULL,
+	ADD COLUMN openings integer NULL,
+	ADD COLUMN islands integer NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE game_session
+	DROP COLUMN left_clicks,
+	DROP COLUMN right_clicks,
+	DROP COLUMN chord_clicks,
+	DROP COLUMN wasted_clicks;
@@ -0,0 +1,5 @@
+ALTER TABLE game_session
+	ADD COLUMN left_clicks integer NOT NULL DEFAULT 0,
+	ADD COLUMN right_clicks integer NOT NULL DEFAULT 0,
+	ADD COLUMN chord_clicks integer NOT NULL DEFAULT 0,
+	ADD COLUMN wasted_clicks integer NOT NULL DEFAULT 0;
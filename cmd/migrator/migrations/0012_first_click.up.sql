@@ -0,0 +1,2 @@
+ALTER TABLE game_session
+	ADD COLUMN first_click text NOT NULL DEFAULT 'opening';
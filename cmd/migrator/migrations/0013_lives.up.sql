@@ -0,0 +1,2 @@
+ALTER TABLE game_session
+	ADD COLUMN lives smallint NOT NULL DEFAULT 0;
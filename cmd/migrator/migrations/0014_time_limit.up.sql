@@ -0,0 +1,2 @@
+ALTER TABLE game_session
+	ADD COLUMN time_limit integer NOT NULL DEFAULT 0;
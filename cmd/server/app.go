@@ -9,16 +9,23 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/vancomm/minesweeper-server/internal/config"
+	"github.com/vancomm/minesweeper-server/internal/mines"
 	"github.com/vancomm/minesweeper-server/internal/repository"
 )
 
 type application struct {
-	logger  *slog.Logger
-	repo    *repository.Queries
-	cookies *config.Cookies
-	jwt     *config.JWT
-	ws      *config.WebSocket
-	rnd     *rand.Rand
+	logger     *slog.Logger
+	repo       *repository.Queries
+	cookies    *config.Cookies
+	jwt        *config.JWT
+	ws         *config.WebSocket
+	rnd        *rand.Rand
+	daily      *dailyBoards
+	limits     mines.Limits
+	presets    *presetRegistry
+	generation *config.Generation
+	pool       *boardPool
+	timers     *gameTimers
 }
 
 func (app application) Router() *mux.Router {
@@ -27,9 +34,18 @@ func (app application) Router() *mux.Router {
 	gameRouter := router.PathPrefix("/game/").Subrouter()
 	gameRouter.Use(app.authenticate)
 	gameRouter.Methods("GET").Path("/highscore").HandlerFunc(app.handleFetchHighScore)
+	gameRouter.Methods("GET").Path("/daily/highscore").HandlerFunc(app.handleFetchDailyHighScore)
+	gameRouter.Methods("POST").Path("/daily").HandlerFunc(app.handleNewDailyGame)
+	gameRouter.Methods("POST").Path("/import").HandlerFunc(app.handleImportGame)
+	gameRouter.Methods("GET").Path("/{id}/hint").HandlerFunc(app.handleHint)
+	gameRouter.Methods("GET").Path("/{id}/probabilities").HandlerFunc(app.handleProbabilities)
+	gameRouter.Methods("GET").Path("/{id}/explain").HandlerFunc(app.handleExplain)
 	gameRouter.Methods("GET").Path("/{id}/connect").HandlerFunc(app.wsConnect)
 	gameRouter.Methods("POST").Path("/{id}/forfeit").HandlerFunc(app.handleForfeit)
 	gameRouter.Methods("POST").Path("/{id}/move").HandlerFunc(app.handleMove)
+	gameRouter.Methods("POST").Path("/{id}/undo").HandlerFunc(app.handleUndo)
+	gameRouter.Methods("POST").Path("/{id}/redo").HandlerFunc(app.handleRedo)
+	gameRouter.Methods("POST").Path("/{id}/solve").HandlerFunc(app.handleSolve)
 	gameRouter.Methods("GET").Path("/{id}").HandlerFunc(app.handleFetchGame)
 	gameRouter.Methods("POST").HandlerFunc(app.handleNewGame)
 
@@ -37,6 +53,8 @@ func (app application) Router() *mux.Router {
 		w.WriteHeader(200)
 		w.Write([]byte("OK"))
 	})
+	router.Methods("GET").Path("/presets").HandlerFunc(app.handleFetchPresets)
+	router.Methods("GET").Path("/pool").HandlerFunc(app.handleFetchPoolStats)
 	router.HandleFunc("/login", app.handleLogin)
 	router.HandleFunc("/register", app.handleRegister)
 	router.HandleFunc("/logout", app.handleLogout)
@@ -60,6 +78,20 @@ func (app application) badRequest(w http.ResponseWriter) {
 	w.Write([]byte("Bad request"))
 }
 
+/*
+Reply with the parameter that was out of range and the rule it
+broke, so that clients can point at the offending field.
+*/
+func (app application) invalidParams(w http.ResponseWriter, err mines.ParamsError) {
+	w.WriteHeader(http.StatusBadRequest)
+	app.replyWithJSON(w, map[string]any{
+		"error":      err.Error(),
+		"field":      err.Field,
+		"constraint": err.Constraint,
+		"limit":      err.Limit,
+	})
+}
+
 func (app application) unauthorized(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusUnauthorized)
 	w.Write([]byte("Unauthorized"))
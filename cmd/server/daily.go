@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vancomm/minesweeper-server/internal/mines"
+	"github.com/vancomm/minesweeper-server/internal/repository"
+)
+
+/*
+Daily boards only come in the built-in presets, so that operators
+adding their own don't change anyone's daily leaderboard.
+*/
+var dailyPresets = func() map[string]mines.GameParams {
+	presets := make(map[string]mines.GameParams)
+	for _, p := range mines.DefaultPresets() {
+		presets[p.Name] = p.GameParams
+	}
+	return presets
+}()
+
+/*
+dailyBoards generates each day's boards on first use and keeps
+them for the rest of the day. Each board has a lock of its own, so
+that generating one preset's board doesn't hold up the others.
+*/
+type dailyBoards struct {
+	secret string
+
+	mu     sync.Mutex
+	date   string
+	boards map[string]*dailyBoard
+}
+
+type dailyBoard struct {
+	mu    sync.Mutex
+	board *mines.GameState /* nil until generated */
+}
+
+func newDailyBoards(secret string) *dailyBoards {
+	return &dailyBoards{secret: secret}
+}
+
+/*
+Every board is generated from a generator seeded with the date,
+the preset name and the server's secret, so everyone gets the same
+layout and start square no matter which instance they ask.
+*/
+func (d *dailyBoards) rand(date string, preset string) *rand.Rand {
+	sum := sha256.Sum256([]byte(d.secret + "/" + date + "/" + preset))
+	return rand.New(rand.NewPCG(
+		binary.LittleEndian.Uint64(sum[:8]), binary.LittleEndian.Uint64(sum[8:16]),
+	))
+}
+
+type generateFunc func(
+	ctx context.Context, params mines.GameParams, x, y int, r *rand.Rand,
+) (*mines.GameState, error)
+
+func (d *dailyBoards) board(
+	ctx context.Context, date string, preset string, generate generateFunc,
+) (*mines.GameState, error) {
+	params, ok := dailyPresets[preset]
+	if !ok {
+		return nil, errUnknownPreset
+	}
+
+	d.mu.Lock()
+	if d.date != date {
+		d.date = date
+		d.boards = make(map[string]*dailyBoard)
+	}
+	entry, ok := d.boards[preset]
+	if !ok {
+		entry = &dailyBoard{}
+		d.boards[preset] = entry
+	}
+	d.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.board == nil {
+		r := d.rand(date, preset)
+		x, y := r.IntN(params.Width), r.IntN(params.Height)
+		board, err := generate(ctx, params, x, y, r)
+		if err != nil {
+			return nil, err
+		}
+		entry.board = board
+	}
+	return entry.board.Clone(), nil
+}
+
+var errUnknownPreset = errors.New("unknown preset")
+
+/* Daily boards change at midnight UTC. */
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+/*
+A daily board's layout is kept secret until the day is over, even
+from those who have finished with it, so that nobody can look at it
+before making the attempt that gets ranked.
+*/
+func dailySecret(s *repository.GameSession) bool {
+	return s.DailyDate.Valid && !s.DailyDate.Time.Before(today())
+}
+
+func (app application) handleNewDailyGame(w http.ResponseWriter, r *http.Request) {
+	preset := r.URL.Query().Get("preset")
+	date := today()
+
+	game, err := app.daily.board(r.Context(), date.Format(time.DateOnly), preset, app.generateGame)
+	if err != nil {
+		if errors.Is(err, errUnknownPreset) {
+			app.badRequest(w)
+		} else if errors.Is(err, mines.ErrGenerationTimeout) {
+			app.generationTimeout(w)
+		} else {
+			app.internalError(w, "unable to generate daily board", slog.Any("error", err))
+		}
+		return
+	}
+
+	sessionParams := repository.CreateGameSessionParams{
+		DailyDate:   &date,
+		DailyPreset: &preset,
+	}
+	if playerId, ok := app.getAuthenticatedPlayerId(r); ok {
+		sessionParams.PlayerId = &playerId
+	}
+
+	session, err := app.repo.CreateGameSession(r.Context(), game, sessionParams)
+	if err != nil {
+		app.internalError(w, "failed to create game session", slog.Any("error", err))
+		return
+	}
+
+	sessionDTO, err := NewGameSessionDTO(*session)
+	if err != nil {
+		app.internalError(w, "failed to create game session dto", slog.Any("error", err))
+		return
+	}
+
+	app.replyWithJSON(w, sessionDTO)
+}
+
+func (app application) handleFetchDailyHighScore(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	preset := query.Get("preset")
+	if _, ok := dailyPresets[preset]; !ok {
+		app.badRequest(w)
+		return
+	}
+
+	date := today()
+	if query.Has("date") {
+		var err error
+		date, err = time.Parse(time.DateOnly, query.Get("date"))
+		if err != nil {
+			app.badRequest(w)
+			return
+		}
+	}
+
+	highscores, err := app.repo.GetDailyHighscores(r.Context(), date, preset)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		app.internalError(w,
+			"failed to fetch daily highscores", slog.Any("err", err),
+			slog.Time("date", date), slog.String("preset", preset),
+		)
+		return
+	}
+
+	app.replyWithJSON(w, highscores)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/vancomm/minesweeper-server/internal/mines"
+)
+
+func TestDailyBoardsGenerateApart(t *testing.T) {
+	daily := newDailyBoards("secret")
+	started, release := make(chan struct{}), make(chan struct{})
+	blocking := func(
+		ctx context.Context, params mines.GameParams, x, y int, r *rand.Rand,
+	) (*mines.GameState, error) {
+		close(started)
+		<-release
+		return mines.NewGame(params, x, y, r)
+	}
+	generate := func(
+		ctx context.Context, params mines.GameParams, x, y int, r *rand.Rand,
+	) (*mines.GameState, error) {
+		return mines.NewGame(params, x, y, r)
+	}
+
+	/* One preset's board takes its time... */
+	done := make(chan error)
+	go func() {
+		_, err := daily.board(context.Background(), "2026-01-01", "expert", blocking)
+		done <- err
+	}()
+	<-started
+
+	/* ...and the others don't wait for it. */
+	ready := make(chan error)
+	go func() {
+		_, err := daily.board(context.Background(), "2026-01-01", "beginner", generate)
+		ready <- err
+	}()
+	select {
+	case err := <-ready:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("one preset's board waited for another's to be generated")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	a, _ := daily.board(context.Background(), "2026-01-01", "beginner", generate)
+	b, _ := daily.board(context.Background(), "2026-01-01", "beginner", generate)
+	if a == b || a.Description() != b.Description() {
+		t.Error("expected copies of the same board")
+	}
+}
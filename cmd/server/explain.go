@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vancomm/minesweeper-server/internal/mines"
+	"github.com/vancomm/minesweeper-server/internal/repository"
+)
+
+type cellDTO struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type traceSetDTO struct {
+	Cells []cellDTO `json:"cells"`
+	Mines int       `json:"mines"`
+}
+
+type stepDTO struct {
+	Kind        string        `json:"kind"`
+	Sets        []traceSetDTO `json:"sets"`
+	Derived     *traceSetDTO  `json:"derived,omitempty"`
+	SquaresLeft int           `json:"squares_left,omitempty"`
+	MinesLeft   int           `json:"mines_left,omitempty"`
+	Proved      []hintCellDTO `json:"proved"`
+}
+
+type explainDTO struct {
+	Move  int       `json:"move"`
+	Guess bool      `json:"guess"`
+	Steps []stepDTO `json:"steps"`
+}
+
+func NewTraceSetDTO(set mines.TraceSet) traceSetDTO {
+	dto := traceSetDTO{Cells: make([]cellDTO, 0, len(set.Squares)), Mines: set.Mines}
+	for _, c := range set.Squares {
+		dto.Cells = append(dto.Cells, cellDTO{X: c.X, Y: c.Y})
+	}
+	return dto
+}
+
+func NewStepDTO(step mines.Step) stepDTO {
+	dto := stepDTO{
+		Kind:        step.Kind.String(),
+		Sets:        make([]traceSetDTO, 0, len(step.Sets)),
+		SquaresLeft: step.SquaresLeft,
+		MinesLeft:   step.MinesLeft,
+		Proved:      make([]hintCellDTO, 0, len(step.Proved)),
+	}
+	for _, set := range step.Sets {
+		dto.Sets = append(dto.Sets, NewTraceSetDTO(set))
+	}
+	if step.Derived != nil {
+		derived := NewTraceSetDTO(*step.Derived)
+		dto.Derived = &derived
+	}
+	for _, h := range step.Proved {
+		dto.Proved = append(dto.Proved, hintCellDTO{X: h.X, Y: h.Y, Mine: h.Mine})
+	}
+	return dto
+}
+
+/*
+Explain the solver's reasoning from a position: every deduction it
+makes, or with x and y, only the steps needed to prove that square.
+A game in progress can only be explained from where it stands, and
+doing so marks it solver-assisted like a hint does. A finished game
+can be explained from any position in its history.
+*/
+func (app application) handleExplain(w http.ResponseWriter, r *http.Request) {
+	sessionId, err := app.getSessionId(r)
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	session, err := app.repo.FetchGameSession(r.Context(), sessionId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			app.notFound(w)
+		} else {
+			app.internalError(w, "could not fetch session from db", slog.Any("error", err))
+		}
+		return
+	}
+
+	playerId, ok := app.getAuthenticatedPlayerId(r)
+	if ok && session.PlayerId != nil && *session.PlayerId != playerId {
+		app.unauthorized(w)
+		return
+	}
+
+	game, err := mines.DecodeGameState(session.State)
+	if err != nil {
+		app.internalError(w, "db returned invalid game_session.state", slog.Any("error", err))
+		return
+	}
+
+	query := r.URL.Query()
+	over := game.Finished()
+
+	/*
+	 * The solver opens squares as it goes, so explaining a
+	 * finished game shows more of the board than the player saw.
+	 */
+	if over && dailySecret(session) {
+		w.WriteHeader(http.StatusConflict)
+		app.replyWithJSON(w, map[string]string{"error": "daily board is still being played"})
+		return
+	}
+
+	var target *point
+	if query.Has("x") || query.Has("y") {
+		p, err := decodePoint(query)
+		if err != nil || p.X < 0 || p.X >= game.Width || p.Y < 0 || p.Y >= game.Height {
+			app.badRequest(w)
+			return
+		}
+		target = &p
+	}
+
+	position, move := game, game.HistoryPos
+	if over {
+		move = max(game.HistoryPos-1, 0)
+		if query.Has("move") {
+			move, err = strconv.Atoi(query.Get("move"))
+			if err != nil {
+				app.badRequest(w)
+				return
+			}
+		}
+		position, ok = game.Position(move)
+		if !ok {
+			app.notFound(w)
+			return
+		}
+	} else if query.Has("move") {
+		w.WriteHeader(http.StatusConflict)
+		app.replyWithJSON(w, map[string]string{"error": "game is not over"})
+		return
+	}
+
+	steps, err := position.Explain()
+	if err != nil {
+		app.internalError(w, "solver failed on player grid", slog.Any("error", err))
+		return
+	}
+
+	if !over {
+		b, err := game.Bytes()
+		if err != nil {
+			app.internalError(w, "unable to serialize game state", slog.Any("error", err))
+			return
+		}
+
+		_, err = app.repo.UpdateGameSession(
+			r.Context(),
+			session.GameSessionId,
+			repository.UpdateGameSessionParams{
+				State:     &b,
+				UsedSolve: &game.UsedSolve,
+			},
+		)
+		if err != nil {
+			app.internalError(w, "unable to update session in db", slog.Any("error", err))
+			return
+		}
+	}
+
+	dto := explainDTO{Move: move, Steps: []stepDTO{}}
+	if target != nil {
+		steps, ok = mines.ExplainSquare(steps, target.X, target.Y)
+		dto.Guess = !ok
+	}
+	for _, step := range steps {
+		dto.Steps = append(dto.Steps, NewStepDTO(step))
+	}
+	app.replyWithJSON(w, dto)
+}
@@ -3,9 +3,12 @@ package main
 import (
 	"errors"
 	"log/slog"
+	"mime"
 	"net/http"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/vancomm/minesweeper-server/internal/mines"
 )
 
 func (app application) handleFetchGame(w http.ResponseWriter, r *http.Request) {
@@ -31,10 +34,53 @@ func (app application) handleFetchGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	/* Timers don't outlive the server; a client checking in restarts one. */
+	app.timers.watch(session)
+
+	if acceptsText(r) {
+		game, err := mines.DecodeGameState(session.State)
+		if err != nil {
+			app.internalError(w, "db returned invalid game_session.state", slog.Any("error", err))
+			return
+		}
+		/*
+		 * Don't hand out the layout of a game that isn't finished,
+		 * or of today's daily board.
+		 */
+		secret := dailySecret(session)
+		if secret {
+			game.PlayerGrid = game.PlayedGrid()
+		}
+		w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(game.Text(game.Finished() && !secret)))
+		return
+	}
+
 	dto, err := NewGameSessionDTO(*session)
 	if err != nil {
 		app.internalError(w, "failed to create game session dto", slog.Any("error", err))
+		return
 	}
 
 	app.replyWithJSON(w, dto)
 }
+
+/*
+Games are served as JSON unless the client asks for plain text
+ahead of it.
+*/
+func acceptsText(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/plain":
+			return true
+		case "application/json", "*/*":
+			return false
+		}
+	}
+	return false
+}
@@ -40,10 +40,13 @@ func (app application) handleForfeit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	game.RevealPlayerGrid()
-	if session.EndedAt.Time.IsZero() {
-		session.EndedAt.Time = time.Now().UTC()
+	if !timeUp(session, game, time.Now()) {
+		game.RevealPlayerGrid()
+		if session.EndedAt.Time.IsZero() {
+			session.EndedAt.Time = time.Now().UTC()
+		}
 	}
+	app.timers.stop(session.GameSessionId)
 
 	b, err := game.Bytes()
 	if err != nil {
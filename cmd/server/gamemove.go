@@ -12,6 +12,7 @@ const (
 	Open GameMove = iota + 1
 	Flag
 	Chord
+	Question
 	LAST_MOVE
 )
 
@@ -36,6 +37,8 @@ func decodeGameMove(s string) (move GameMove, err error) {
 		move = Flag
 	case "chord":
 		move = Chord
+	case "question":
+		move = Question
 	default:
 		err = ErrBadMove
 	}
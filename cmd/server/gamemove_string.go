@@ -11,12 +11,13 @@ func _() {
 	_ = x[Open-1]
 	_ = x[Flag-2]
 	_ = x[Chord-3]
-	_ = x[LAST_MOVE-4]
+	_ = x[Question-4]
+	_ = x[LAST_MOVE-5]
 }
 
-const _GameMove_name = "OpenFlagChordLAST_MOVE"
+const _GameMove_name = "OpenFlagChordQuestionLAST_MOVE"
 
-var _GameMove_index = [...]uint8{0, 4, 8, 13, 22}
+var _GameMove_index = [...]uint8{0, 4, 8, 13, 21, 30}
 
 func (i GameMove) String() string {
 	i -= 1
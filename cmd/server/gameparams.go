@@ -1,12 +1,24 @@
 package main
 
-import "github.com/gorilla/schema"
+import (
+	"github.com/gorilla/schema"
+	"github.com/vancomm/minesweeper-server/internal/mines"
+)
 
 type GameParams struct {
-	Width     int  `schema:"width,required"`
-	Height    int  `schema:"height,required"`
-	MineCount int  `schema:"mine_count,required"`
-	Unique    bool `schema:"unique,required"`
+	Width     int          `schema:"width,required"`
+	Height    int          `schema:"height,required"`
+	MineCount int          `schema:"mine_count,required"`
+	Unique    bool         `schema:"unique,required"`
+	Tiling    mines.Tiling `schema:"tiling"`
+	Wrap      bool         `schema:"wrap"`
+
+	MinesPerCell int         `schema:"mines_per_cell"`
+	MaxGrade     mines.Grade `schema:"max_grade"`
+
+	FirstClick mines.FirstClick `schema:"first_click"`
+	Lives      int              `schema:"lives"`
+	TimeLimit  int              `schema:"time_limit"`
 }
 
 func decodeGameParams(src map[string][]string) (GameParams, error) {
@@ -1,9 +1,8 @@
 package main
 
 import (
-	"bytes"
-	"encoding/gob"
 	"strconv"
+	"time"
 
 	"github.com/vancomm/minesweeper-server/internal/mines"
 	"github.com/vancomm/minesweeper-server/internal/repository"
@@ -16,16 +15,65 @@ type gameSessionDTO struct {
 	Height        int        `json:"height"`
 	MineCount     int        `json:"mine_count"`
 	Unique        bool       `json:"unique"`
+	Tiling        string     `json:"tiling"`
+	Wrap          bool       `json:"wrap"`
+	MinesPerCell  int        `json:"mines_per_cell"`
+	FirstClick    string     `json:"first_click"`
+	Start         *cellDTO   `json:"start,omitempty"`
+	Lives         int        `json:"lives"`
+	LivesLeft     int        `json:"lives_left"`
+	TimeLimit     int        `json:"time_limit"`
+	Deadline      *int64     `json:"deadline,omitempty"`
+	TimeUp        bool       `json:"time_up"`
 	Dead          bool       `json:"dead"`
 	Won           bool       `json:"won"`
+	QuestionMarks bool       `json:"question_marks"`
+	UsedUndo      bool       `json:"used_undo"`
+	CanUndo       bool       `json:"can_undo"`
+	CanRedo       bool       `json:"can_redo"`
+	UsedSolve     bool       `json:"used_solve"`
+	CustomLayout  bool       `json:"custom_layout"`
+	DailyDate     *string    `json:"daily_date,omitempty"`
+	DailyPreset   *string    `json:"daily_preset,omitempty"`
 	StartedAt     int64      `json:"started_at"`
 	EndedAt       *int64     `json:"ended_at,omitempty"`
+	Description   *string    `json:"description,omitempty"`
+	Bbbv          *int       `json:"bbbv,omitempty"`
+	Openings      *int       `json:"openings,omitempty"`
+	Islands       *int       `json:"islands,omitempty"`
+	Grade         *string    `json:"grade,omitempty"`
+	Stats         *statsDTO  `json:"stats,omitempty"`
+	Hint          *hintDTO   `json:"hint,omitempty"`
+}
+
+type statsDTO struct {
+	LeftClicks    int     `json:"left_clicks"`
+	RightClicks   int     `json:"right_clicks"`
+	ChordClicks   int     `json:"chord_clicks"`
+	WastedClicks  int     `json:"wasted_clicks"`
+	BbbvPerSecond float64 `json:"bbbv_per_s"`
+	Ioe           float64 `json:"ioe"`
+	Correctness   float64 `json:"correctness"`
+	FlagAccuracy  float64 `json:"flag_accuracy"`
+}
+
+func NewStatsDTO(state *mines.GameState, elapsed time.Duration) *statsDTO {
+	e := state.Efficiency(elapsed)
+	return &statsDTO{
+		LeftClicks:    state.Clicks.Left,
+		RightClicks:   state.Clicks.Right,
+		ChordClicks:   state.Clicks.Chord,
+		WastedClicks:  state.Clicks.Wasted,
+		BbbvPerSecond: e.BBBVPerSecond,
+		Ioe:           e.IOE,
+		Correctness:   e.Correctness,
+		FlagAccuracy:  e.FlagAccuracy,
+	}
 }
 
 func NewGameSessionDTO(s repository.GameSession) (*gameSessionDTO, error) {
-	var state mines.GameState
-	buf := bytes.NewBuffer(s.State)
-	if err := gob.NewDecoder(buf).Decode(&state); err != nil {
+	state, err := mines.DecodeGameState(s.State)
+	if err != nil {
 		return nil, err
 	}
 
@@ -35,17 +83,100 @@ func NewGameSessionDTO(s repository.GameSession) (*gameSessionDTO, error) {
 		endedAt = &e
 	}
 
+	var dailyDate *string
+	if s.DailyDate.Valid {
+		d := s.DailyDate.Time.Format(time.DateOnly)
+		dailyDate = &d
+	}
+
+	/*
+	 * Don't hand out the layout of a game that's still going, or
+	 * of a loss that can still be undone, or of today's daily
+	 * board, or anything that gives it away.
+	 */
+	grid := state.PlayerGrid
+	secret := dailySecret(&s)
+	if secret && (state.Dead || state.Won) {
+		grid = state.PlayedGrid()
+	}
+	var description *string
+	var bbbv, openings, islands *int
+	var stats *statsDTO
+	if state.Finished() && !secret {
+		d := state.Description()
+		description = &d
+		bbbv, openings, islands = s.Bbbv, s.Openings, s.Islands
+		if !s.EndedAt.Time.IsZero() {
+			stats = NewStatsDTO(state, s.EndedAt.Time.Sub(s.StartedAt.Time))
+		}
+	}
+
+	/*
+	 * How hard the board is says nothing about where the mines
+	 * are, so it can be shown from the start.
+	 */
+	var grade *string
+	if s.Grade != nil {
+		g := mines.Grade(*s.Grade).String()
+		grade = &g
+	}
+
+	/*
+	 * Where the server chose the start square, the client shows
+	 * it for the player to open.
+	 */
+	var start *cellDTO
+	if state.FirstClick == mines.FirstClickChosen {
+		start = &cellDTO{X: state.StartX, Y: state.StartY}
+	}
+
+	/*
+	 * The server's deadline is the one that counts, so clients
+	 * should count down to it rather than time the game themselves.
+	 */
+	var deadline *int64
+	var timedOut bool
+	if d, ok := sessionDeadline(&s); ok {
+		ms := d.UnixMilli()
+		deadline = &ms
+		timedOut = s.Dead && !s.EndedAt.Time.IsZero() && !s.EndedAt.Time.Before(d)
+	}
+
 	dto := &gameSessionDTO{
 		GameSessionId: strconv.Itoa(s.GameSessionId),
-		Grid:          state.PlayerGrid,
+		Grid:          grid,
 		Width:         s.Width,
 		Height:        s.Height,
 		MineCount:     s.MineCount,
 		Unique:        s.Unique,
+		Tiling:        s.Tiling,
+		Wrap:          s.Wrap,
+		MinesPerCell:  s.MinesPerCell,
+		FirstClick:    s.FirstClick,
+		Start:         start,
+		Lives:         s.Lives,
+		LivesLeft:     state.LivesLeft(),
+		TimeLimit:     s.TimeLimit,
+		Deadline:      deadline,
+		TimeUp:        timedOut,
 		Dead:          s.Dead,
 		Won:           s.Won,
+		QuestionMarks: state.QuestionMarks,
+		UsedUndo:      s.UsedUndo,
+		CanUndo:       state.CanUndo(),
+		CanRedo:       state.CanRedo(),
+		UsedSolve:     s.UsedSolve,
+		CustomLayout:  s.CustomLayout,
+		DailyDate:     dailyDate,
+		DailyPreset:   s.DailyPreset,
 		StartedAt:     s.StartedAt.Time.UnixMilli(),
 		EndedAt:       endedAt,
+		Description:   description,
+		Bbbv:          bbbv,
+		Openings:      openings,
+		Islands:       islands,
+		Grade:         grade,
+		Stats:         stats,
 	}
 	return dto, nil
 }
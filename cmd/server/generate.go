@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+
+	"github.com/vancomm/minesweeper-server/internal/mines"
+)
+
+/*
+Generate a board within the configured budget, logging how much
+work it took.
+*/
+func (app application) generateGame(
+	ctx context.Context, params mines.GameParams, x, y int, r *rand.Rand,
+) (*mines.GameState, error) {
+	ctx, cancel := context.WithTimeout(ctx, app.generation.Timeout)
+	defer cancel()
+
+	game, stats, err := mines.NewGameContext(ctx, params, x, y, app.generation.MaxAttempts, r)
+	attrs := []any{
+		slog.String("seed", params.Seed()),
+		slog.Int("attempts", stats.Attempts),
+		slog.Duration("duration", stats.Duration),
+	}
+	if err != nil {
+		app.logger.Warn("board generation failed", append(attrs, slog.Any("error", err))...)
+	} else {
+		app.logger.Info("board generated", attrs...)
+	}
+	return game, err
+}
+
+func (app application) generationTimeout(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusServiceUnavailable)
+	app.replyWithJSON(w, map[string]string{"error": mines.ErrGenerationTimeout.Error()})
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/vancomm/minesweeper-server/internal/mines"
@@ -21,6 +22,13 @@ func (app application) handleFetchHighScore(w http.ResponseWriter, r *http.Reque
 			return
 		}
 		filter.GameParams = gameParams
+	} else if query.Has("preset") {
+		gameParams, ok := app.presets.lookup(query.Get("preset"))
+		if !ok {
+			app.badRequest(w)
+			return
+		}
+		filter.GameParams = &gameParams
 	}
 
 	if query.Has("username") {
@@ -28,6 +36,24 @@ func (app application) handleFetchHighScore(w http.ResponseWriter, r *http.Reque
 		filter.Username = &username
 	}
 
+	if query.Has("min_bbbv") {
+		minBbbv, err := strconv.Atoi(query.Get("min_bbbv"))
+		if err != nil {
+			app.badRequest(w)
+			return
+		}
+		filter.MinBbbv = &minBbbv
+	}
+
+	if query.Has("max_bbbv") {
+		maxBbbv, err := strconv.Atoi(query.Get("max_bbbv"))
+		if err != nil {
+			app.badRequest(w)
+			return
+		}
+		filter.MaxBbbv = &maxBbbv
+	}
+
 	highscores, err := app.repo.GetHighscores(r.Context(), filter)
 	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 		app.internalError(w,
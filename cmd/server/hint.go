@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vancomm/minesweeper-server/internal/mines"
+	"github.com/vancomm/minesweeper-server/internal/repository"
+)
+
+type hintCellDTO struct {
+	X    int  `json:"x"`
+	Y    int  `json:"y"`
+	Mine bool `json:"mine"`
+}
+
+type hintDTO struct {
+	Guess bool         `json:"guess"`
+	Cell  *hintCellDTO `json:"cell"`
+}
+
+func NewHintDTO(hint *mines.Hint) *hintDTO {
+	if hint == nil {
+		return &hintDTO{Guess: true}
+	}
+	return &hintDTO{
+		Cell: &hintCellDTO{X: hint.X, Y: hint.Y, Mine: hint.Mine},
+	}
+}
+
+func (app application) handleHint(w http.ResponseWriter, r *http.Request) {
+	sessionId, err := app.getSessionId(r)
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	session, err := app.repo.FetchGameSession(r.Context(), sessionId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			app.notFound(w)
+		} else {
+			app.internalError(w, "could not fetch session from db", slog.Any("error", err))
+		}
+		return
+	}
+
+	playerId, ok := app.getAuthenticatedPlayerId(r)
+	if ok && session.PlayerId != nil && *session.PlayerId != playerId {
+		app.unauthorized(w)
+		return
+	}
+
+	game, err := mines.DecodeGameState(session.State)
+	if err != nil {
+		app.internalError(w, "db returned invalid game_session.state", slog.Any("error", err))
+		return
+	}
+
+	hint, err := game.Hint()
+	if errors.Is(err, mines.ErrGameOver) {
+		w.WriteHeader(http.StatusConflict)
+		app.replyWithJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		app.internalError(w, "solver failed on player grid", slog.Any("error", err))
+		return
+	}
+
+	b, err := game.Bytes()
+	if err != nil {
+		app.internalError(w, "unable to serialize game state", slog.Any("error", err))
+		return
+	}
+
+	_, err = app.repo.UpdateGameSession(
+		r.Context(),
+		session.GameSessionId,
+		repository.UpdateGameSessionParams{
+			State:     &b,
+			UsedSolve: &game.UsedSolve,
+		},
+	)
+	if err != nil {
+		app.internalError(w, "unable to update session in db", slog.Any("error", err))
+		return
+	}
+
+	app.replyWithJSON(w, NewHintDTO(hint))
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/vancomm/minesweeper-server/internal/mines"
+	"github.com/vancomm/minesweeper-server/internal/repository"
+)
+
+/* Big enough for the largest board the limits allow. */
+const maxLayoutBytes = 1 << 20
+
+/*
+A hand-made board sent as JSON. The grid gives the number of mines
+in each square, a row at a time; the size of the board and its mine
+count are taken from it.
+*/
+type layoutDTO struct {
+	Grid         [][]int8     `json:"grid"`
+	X            int          `json:"x"`
+	Y            int          `json:"y"`
+	Unique       bool         `json:"unique"`
+	Tiling       mines.Tiling `json:"tiling"`
+	Wrap         bool         `json:"wrap"`
+	MinesPerCell int          `json:"mines_per_cell"`
+	MaxGrade     mines.Grade  `json:"max_grade"`
+
+	FirstClick mines.FirstClick `json:"first_click"`
+	Lives      int              `json:"lives"`
+	TimeLimit  int              `json:"time_limit"`
+}
+
+func (l layoutDTO) unpack() (params mines.GameParams, grid []int8, ok bool) {
+	params = mines.GameParams{
+		Height:       len(l.Grid),
+		Unique:       l.Unique,
+		Tiling:       l.Tiling,
+		Wrap:         l.Wrap,
+		MinesPerCell: l.MinesPerCell,
+		MaxGrade:     l.MaxGrade,
+		FirstClick:   l.FirstClick,
+		Lives:        l.Lives,
+		TimeLimit:    l.TimeLimit,
+	}
+	if len(l.Grid) == 0 || len(l.Grid[0]) == 0 {
+		return params, nil, false
+	}
+	params.Width = len(l.Grid[0])
+	for _, row := range l.Grid {
+		if len(row) != params.Width {
+			return params, nil, false
+		}
+		for _, n := range row {
+			params.MineCount += int(n)
+		}
+		grid = append(grid, row...)
+	}
+	return params, grid, true
+}
+
+type importDTO struct {
+	Solvable   bool            `json:"solvable"`
+	Techniques string          `json:"techniques"`
+	Grade      string          `json:"grade"`
+	Session    *gameSessionDTO `json:"session,omitempty"`
+}
+
+/*
+Import a hand-made board, either as JSON or in the layout text
+format, and check it with the solver. Unless the request is only a
+dry run, a board that passes becomes a new session. A board that
+claims to be unique but needs guessing, or is harder than the grade
+it gives, is refused with the solver's verdict.
+*/
+func (app application) handleImportGame(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var dryRun bool
+	var err error
+	if query.Has("dry_run") {
+		dryRun, err = strconv.ParseBool(query.Get("dry_run"))
+		if err != nil {
+			app.badRequest(w)
+			return
+		}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxLayoutBytes))
+	if err != nil {
+		app.badRequest(w)
+		return
+	}
+
+	var params mines.GameParams
+	var grid []int8
+	var x, y int
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		var dto layoutDTO
+		if err := json.Unmarshal(body, &dto); err != nil {
+			app.badRequest(w)
+			return
+		}
+		var ok bool
+		params, grid, ok = dto.unpack()
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			app.replyWithJSON(w, map[string]string{"error": "grid must be a non-empty rectangle"})
+			return
+		}
+		x, y = dto.X, dto.Y
+	} else {
+		params, grid, x, y, err = mines.ParseLayout(string(body), app.limits)
+		var paramsErr mines.ParamsError
+		if errors.As(err, &paramsErr) {
+			app.invalidParams(w, paramsErr)
+			return
+		} else if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			app.replyWithJSON(w, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := params.ValidateLayout(app.limits); err != nil {
+		var paramsErr mines.ParamsError
+		if errors.As(err, &paramsErr) {
+			app.invalidParams(w, paramsErr)
+		} else {
+			app.badRequest(w)
+		}
+		return
+	}
+
+	game, techniques, err := mines.NewGameFromLayout(params, grid, x, y)
+	if game == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		app.replyWithJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+
+	verdict := importDTO{
+		Solvable:   techniques&mines.TechniqueGuess == 0,
+		Techniques: techniques.String(),
+		Grade:      techniques.Grade().String(),
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		app.replyWithJSON(w, map[string]any{"error": err.Error(), "verdict": verdict})
+		return
+	}
+	if dryRun {
+		app.replyWithJSON(w, verdict)
+		return
+	}
+
+	var sessionParams repository.CreateGameSessionParams
+	if playerId, ok := app.getAuthenticatedPlayerId(r); ok {
+		sessionParams.PlayerId = &playerId
+	}
+
+	session, err := app.repo.CreateGameSession(r.Context(), game, sessionParams)
+	if err != nil {
+		app.internalError(w, "failed to create game session", slog.Any("error", err))
+		return
+	}
+	app.timers.watch(session)
+
+	verdict.Session, err = NewGameSessionDTO(*session)
+	if err != nil {
+		app.internalError(w, "failed to create game session dto", slog.Any("error", err))
+		return
+	}
+
+	app.replyWithJSON(w, verdict)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/vancomm/minesweeper-server/internal/config"
 	"github.com/vancomm/minesweeper-server/internal/database"
 	"github.com/vancomm/minesweeper-server/internal/middleware"
+	"github.com/vancomm/minesweeper-server/internal/mines"
 	"github.com/vancomm/minesweeper-server/internal/repository"
 )
 
@@ -52,15 +53,66 @@ func main() {
 		return
 	}
 
+	daily, err := config.NewDaily()
+	if err != nil {
+		logger.Error("failed to read daily config", "error", err)
+		return
+	}
+	if daily.Secret == "" {
+		logger.Warn("DAILY_SECRET is not set, daily boards can be predicted")
+	}
+
+	limits, err := config.NewLimits()
+	if err != nil {
+		logger.Error("failed to read limits config", "error", err)
+		return
+	}
+
+	generation, err := config.NewGeneration()
+	if err != nil {
+		logger.Error("failed to read generation config", "error", err)
+		return
+	}
+
+	customPresets, err := config.NewPresets()
+	if err != nil {
+		logger.Error("failed to read presets config", "error", err)
+		return
+	}
+	presets, err := newPresetRegistry(customPresets, mines.Limits(*limits))
+	if err != nil {
+		logger.Error("invalid preset", "error", err)
+		return
+	}
+
+	poolConfig, err := config.NewPool()
+	if err != nil {
+		logger.Error("failed to read pool config", "error", err)
+		return
+	}
+	pool, err := newBoardPool(logger, *poolConfig, *generation, presets)
+	if err != nil {
+		logger.Error("invalid pool config", "error", err)
+		return
+	}
+	pool.run(ctx, poolConfig.Workers)
+
 	port := config.Port()
 
+	repo := repository.New(db)
 	app := &application{
-		logger:  logger,
-		repo:    repository.New(db),
-		ws:      ws,
-		cookies: cookies,
-		jwt:     jwt,
-		rnd:     createRand(),
+		logger:     logger,
+		repo:       repo,
+		ws:         ws,
+		cookies:    cookies,
+		jwt:        jwt,
+		rnd:        createRand(),
+		daily:      newDailyBoards(daily.Secret),
+		limits:     mines.Limits(*limits),
+		presets:    presets,
+		generation: generation,
+		pool:       pool,
+		timers:     newGameTimers(logger, repo),
 	}
 	router := app.Router()
 	router.Use(middleware.Cors(), middleware.Logging(logger))
@@ -58,20 +58,40 @@ func (app application) handleMove(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	switch move {
-	case Open:
-		game.OpenCell(p.X, p.Y)
-	case Flag:
-		game.FlagCell(p.X, p.Y)
-	case Chord:
-		game.ChordCell(p.X, p.Y)
-	default:
-		app.logger.Warn("unhandled GameMove", slog.Any("move", move))
+	if timeUp(session, game, time.Now()) {
+		/* The move came too late to count, and the game is lost. */
+	} else {
+		switch move {
+		case Open:
+			err = game.Do(mines.Move{Kind: mines.MoveOpen, X: p.X, Y: p.Y})
+		case Flag:
+			err = game.Do(mines.Move{Kind: mines.MoveFlag, X: p.X, Y: p.Y})
+		case Chord:
+			err = game.Do(mines.Move{Kind: mines.MoveChord, X: p.X, Y: p.Y})
+		case Question:
+			err = game.Do(mines.Move{Kind: mines.MoveQuestion, X: p.X, Y: p.Y})
+		default:
+			app.logger.Warn("unhandled GameMove", slog.Any("move", move))
+		}
+		if err != nil {
+			app.internalError(w, "unable to make a move", slog.Any("error", err))
+			return
+		}
 	}
 
 	if game.Won || game.Dead {
-		game.RevealPlayerGrid()
-		session.EndedAt.Time = time.Now().UTC()
+		/*
+		 * A loss that can still be undone shows only the mine that
+		 * went off, so that taking it back doesn't come with the
+		 * rest of the layout.
+		 */
+		if game.Finished() {
+			game.RevealPlayerGrid()
+		}
+		if session.EndedAt.Time.IsZero() {
+			session.EndedAt.Time = time.Now().UTC()
+		}
+		app.timers.stop(session.GameSessionId)
 	}
 
 	b, err := game.Bytes()
@@ -88,6 +108,7 @@ func (app application) handleMove(w http.ResponseWriter, r *http.Request) {
 			Won:     &game.Won,
 			EndedAt: &session.EndedAt.Time,
 			State:   &b,
+			Clicks:  &game.Clicks,
 		},
 	)
 	if err != nil {
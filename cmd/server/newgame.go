@@ -1,8 +1,10 @@
 package main
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/vancomm/minesweeper-server/internal/mines"
 	"github.com/vancomm/minesweeper-server/internal/repository"
@@ -11,29 +13,85 @@ import (
 func (app application) handleNewGame(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 
-	params, err := decodeGameParams(query)
-	if err != nil {
-		app.badRequest(w)
-		return
+	var gameParams mines.GameParams
+	if query.Has("preset") {
+		var ok bool
+		gameParams, ok = app.presets.lookup(query.Get("preset"))
+		if !ok {
+			app.badRequest(w)
+			return
+		}
+	} else {
+		params, err := decodeGameParams(query)
+		if err != nil {
+			app.badRequest(w)
+			return
+		}
+		gameParams = mines.GameParams(params)
 	}
 
-	p, err := decodePoint(query)
-	if err != nil {
-		app.badRequest(w)
-		return
+	p, pointErr := decodePoint(query)
+
+	var questionMarks bool
+	var err error
+	if query.Has("question_marks") {
+		questionMarks, err = strconv.ParseBool(query.Get("question_marks"))
+		if err != nil {
+			app.badRequest(w)
+			return
+		}
 	}
 
-	gameParams := mines.GameParams(params)
-	if !gameParams.PointInBounds(p.X, p.Y) {
-		app.badRequest(w)
+	if err := gameParams.Validate(app.limits); err != nil {
+		var paramsErr mines.ParamsError
+		if errors.As(err, &paramsErr) {
+			app.invalidParams(w, paramsErr)
+		} else {
+			app.badRequest(w)
+		}
 		return
 	}
 
-	game, err := mines.NewGame(gameParams, p.X, p.Y, app.rnd)
-	if err != nil {
-		app.internalError(w, "unable to generate a new game", slog.Any("error", err))
-		return
+	var game *mines.GameState
+	if query.Has("desc") {
+		/*
+		 * Replaying a shared board: the description carries the
+		 * layout and usually the start square as well.
+		 */
+		x, y := -1, -1
+		if pointErr == nil {
+			x, y = p.X, p.Y
+		}
+		game, err = mines.NewGameFromDesc(gameParams, query.Get("desc"), x, y)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			app.replyWithJSON(w, map[string]string{"error": err.Error()})
+			return
+		}
+	} else {
+		/* A server-chosen start needs no click to start from. */
+		if pointErr != nil && gameParams.FirstClick == mines.FirstClickChosen {
+			p, pointErr = point{}, nil
+		}
+		if pointErr != nil || !gameParams.PointInBounds(p.X, p.Y) {
+			app.badRequest(w)
+			return
+		}
+		var ok bool
+		game, ok = app.pool.take(gameParams, p.X, p.Y)
+		if !ok {
+			game, err = app.generateGame(r.Context(), gameParams, p.X, p.Y, app.rnd)
+		}
+		if errors.Is(err, mines.ErrGenerationTimeout) {
+			app.generationTimeout(w)
+			return
+		}
+		if err != nil {
+			app.internalError(w, "unable to generate a new game", slog.Any("error", err))
+			return
+		}
 	}
+	game.QuestionMarks = questionMarks
 
 	var sessionParams repository.CreateGameSessionParams
 	if playerId, ok := app.getAuthenticatedPlayerId(r); ok {
@@ -45,6 +103,7 @@ func (app application) handleNewGame(w http.ResponseWriter, r *http.Request) {
 		app.internalError(w, "failed to create game session", slog.Any("error", err))
 		return
 	}
+	app.timers.watch(session)
 
 	sessionDTO, err := NewGameSessionDTO(*session)
 	if err != nil {
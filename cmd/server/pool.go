@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/vancomm/minesweeper-server/internal/config"
+	"github.com/vancomm/minesweeper-server/internal/mines"
+)
+
+/*
+boardPool keeps a stock of boards generated in the background for
+the busiest presets, so that most new games on them can start
+without waiting for the generator.
+
+A board serves any first click that lands in the opening it was
+generated around (see [mines.GameState.MoveStart]). Clicks that miss
+every board in stock are remembered while the stock has room for
+them, and the next boards for that preset are generated around them,
+so the stock drifts towards where players actually start. Boards in
+stock are never dropped for them: on presets with small openings
+most clicks miss, and the stock would never fill.
+*/
+type boardPool struct {
+	logger     *slog.Logger
+	generation config.Generation
+	size       int
+	wake       chan struct{}
+
+	mu     sync.Mutex
+	stocks []*poolStock
+}
+
+type poolStock struct {
+	name    string
+	params  mines.GameParams
+	boards  []*mines.GameState
+	pending int     /* boards being generated */
+	wanted  []point /* start squares that missed, to generate around next */
+
+	hits, misses int
+	refills      int
+	refillTime   time.Duration /* spent generating all refills */
+	lastRefill   time.Duration
+}
+
+func newBoardPool(
+	logger *slog.Logger, cfg config.Pool, generation config.Generation, presets *presetRegistry,
+) (*boardPool, error) {
+	pool := &boardPool{
+		logger:     logger,
+		generation: generation,
+		size:       cfg.Size,
+		wake:       make(chan struct{}, 1),
+	}
+	for _, name := range cfg.Presets {
+		params, ok := presets.lookup(name)
+		if !ok {
+			return nil, fmt.Errorf(`no preset "%s" to pool boards for`, name)
+		}
+		pool.stocks = append(pool.stocks, &poolStock{name: name, params: params})
+	}
+	return pool, nil
+}
+
+/*
+Start the workers, which keep filling the pool until ctx is done.
+*/
+func (p *boardPool) run(ctx context.Context, workers int) {
+	for range workers {
+		go p.work(ctx, createRand())
+	}
+	p.signal()
+}
+
+func (p *boardPool) signal() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *boardPool) work(ctx context.Context, r *rand.Rand) {
+	for {
+		stock, start, ok := p.next(r)
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.wake:
+				continue
+			}
+		}
+
+		gctx, cancel := context.WithTimeout(ctx, p.generation.Timeout)
+		board, stats, err := mines.NewGameContext(
+			gctx, stock.params, start.X, start.Y, p.generation.MaxAttempts, r,
+		)
+		cancel()
+
+		p.mu.Lock()
+		stock.pending--
+		if err == nil {
+			stock.boards = append(stock.boards, board)
+			stock.refills++
+			stock.refillTime += stats.Duration
+			stock.lastRefill = stats.Duration
+		}
+		p.mu.Unlock()
+
+		if ctx.Err() != nil {
+			return
+		}
+		attrs := []any{
+			slog.String("preset", stock.name),
+			slog.Int("attempts", stats.Attempts),
+			slog.Duration("duration", stats.Duration),
+		}
+		if err != nil {
+			p.logger.Warn("pool refill failed", append(attrs, slog.Any("error", err))...)
+		} else {
+			p.logger.Debug("pool refilled", attrs...)
+		}
+	}
+}
+
+/*
+Claim the next board to generate: one for the preset with the
+fewest in stock, around a start square that missed if there is
+one and a random square otherwise.
+*/
+func (p *boardPool) next(r *rand.Rand) (*poolStock, point, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var stock *poolStock
+	for _, s := range p.stocks {
+		if n := len(s.boards) + s.pending; n < p.size &&
+			(stock == nil || n < len(stock.boards)+stock.pending) {
+			stock = s
+		}
+	}
+	if stock == nil {
+		return nil, point{}, false
+	}
+
+	var start point
+	if len(stock.wanted) > 0 {
+		start = stock.wanted[0]
+		stock.wanted = stock.wanted[1:]
+	} else {
+		start = point{X: r.IntN(stock.params.Width), Y: r.IntN(stock.params.Height)}
+	}
+	stock.pending++
+
+	/* Let another worker pick up whatever is left. */
+	p.signal()
+	return stock, start, true
+}
+
+/*
+Take a board in stock that can start from (x,y), if the params are
+those of a pooled preset and there is one.
+*/
+func (p *boardPool) take(params mines.GameParams, x, y int) (*mines.GameState, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	i := slices.IndexFunc(p.stocks, func(s *poolStock) bool { return s.params == params })
+	if i < 0 {
+		return nil, false
+	}
+	stock := p.stocks[i]
+
+	for j, board := range stock.boards {
+		if board.MoveStart(x, y) {
+			stock.boards = slices.Delete(stock.boards, j, j+1)
+			stock.hits++
+			p.signal()
+			return board, true
+		}
+	}
+
+	stock.misses++
+	start := point{X: x, Y: y}
+	free := p.size - len(stock.boards) - stock.pending - len(stock.wanted)
+	if free > 0 && !slices.Contains(stock.wanted, start) {
+		stock.wanted = append(stock.wanted, start)
+	}
+	p.signal()
+	return nil, false
+}
+
+type poolStatsDTO struct {
+	Preset       string  `json:"preset"`
+	Stock        int     `json:"stock"`
+	Capacity     int     `json:"capacity"`
+	Pending      int     `json:"pending"`
+	Hits         int     `json:"hits"`
+	Misses       int     `json:"misses"`
+	HitRate      float64 `json:"hit_rate"`
+	Refills      int     `json:"refills"`
+	MeanRefillMs float64 `json:"mean_refill_ms"`
+	LastRefillMs float64 `json:"last_refill_ms"`
+}
+
+func (p *boardPool) stats() []poolStatsDTO {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	dtos := make([]poolStatsDTO, 0, len(p.stocks))
+	for _, s := range p.stocks {
+		dto := poolStatsDTO{
+			Preset:       s.name,
+			Stock:        len(s.boards),
+			Capacity:     p.size,
+			Pending:      s.pending,
+			Hits:         s.hits,
+			Misses:       s.misses,
+			Refills:      s.refills,
+			LastRefillMs: float64(s.lastRefill) / float64(time.Millisecond),
+		}
+		if s.hits+s.misses > 0 {
+			dto.HitRate = float64(s.hits) / float64(s.hits+s.misses)
+		}
+		if s.refills > 0 {
+			dto.MeanRefillMs = float64(s.refillTime) / float64(s.refills) / float64(time.Millisecond)
+		}
+		dtos = append(dtos, dto)
+	}
+	return dtos
+}
+
+func (app application) handleFetchPoolStats(w http.ResponseWriter, r *http.Request) {
+	app.replyWithJSON(w, app.pool.stats())
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/vancomm/minesweeper-server/internal/mines"
+)
+
+func newTestPool(t *testing.T, size int, boards int) (*boardPool, *poolStock) {
+	params := mines.GameParams{Width: 9, Height: 9, MineCount: 10}
+	r := rand.New(rand.NewPCG(1, 2))
+	stock := &poolStock{name: "beginner", params: params}
+	for range boards {
+		board, err := mines.NewGame(params, 4, 4, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stock.boards = append(stock.boards, board)
+	}
+	pool := &boardPool{
+		logger: slog.Default(),
+		size:   size,
+		wake:   make(chan struct{}, 1),
+		stocks: []*poolStock{stock},
+	}
+	return pool, stock
+}
+
+/*
+A square that is not an open blank on any board in stock, so that
+a first click there misses them all.
+*/
+func missingSquare(t *testing.T, stock *poolStock) point {
+	params := stock.params
+	for i := range params.Width * params.Height {
+		missed := true
+		for _, board := range stock.boards {
+			if board.PlayerGrid[i] == 0 {
+				missed = false
+			}
+		}
+		if missed {
+			return point{X: i % params.Width, Y: i / params.Width}
+		}
+	}
+	t.Fatal("every square is open on some board")
+	return point{}
+}
+
+func TestPoolMissOnFullStock(t *testing.T) {
+	pool, stock := newTestPool(t, 2, 2)
+	miss := missingSquare(t, stock)
+
+	if _, ok := pool.take(stock.params, miss.X, miss.Y); ok {
+		t.Fatal("took a board that cannot start from the square")
+	}
+	if len(stock.boards) != 2 || len(stock.wanted) != 0 || stock.misses != 1 {
+		t.Fatalf(
+			"a miss on a full stock should change nothing but the count: %d boards, wanted %v",
+			len(stock.boards), stock.wanted,
+		)
+	}
+	if _, _, ok := pool.next(rand.New(rand.NewPCG(1, 2))); ok {
+		t.Error("a full stock should have nothing to generate")
+	}
+}
+
+func TestPoolTakeAndRefill(t *testing.T) {
+	pool, stock := newTestPool(t, 2, 2)
+	r := rand.New(rand.NewPCG(3, 4))
+
+	board, ok := pool.take(stock.params, 4, 4)
+	if !ok || board.StartX != 4 || board.StartY != 4 || len(stock.boards) != 1 || stock.hits != 1 {
+		t.Fatal("expected to take a board generated around the click")
+	}
+
+	miss := missingSquare(t, stock)
+	pool.take(stock.params, miss.X, miss.Y)
+	pool.take(stock.params, miss.X, miss.Y)
+	if len(stock.wanted) != 1 || stock.wanted[0] != miss {
+		t.Fatalf("expected the missed square to be wanted once, got %v", stock.wanted)
+	}
+
+	s, start, ok := pool.next(r)
+	if !ok || s != stock || start != miss || stock.pending != 1 || len(stock.wanted) != 0 {
+		t.Fatalf("expected a board around the missed square, got %v", start)
+	}
+	if _, _, ok := pool.next(r); ok {
+		t.Error("a stock with a board pending should be full")
+	}
+
+	/* With the stock full again, misses are only counted. */
+	pool.take(stock.params, miss.X, miss.Y)
+	if len(stock.wanted) != 0 || stock.misses != 3 {
+		t.Errorf("a miss with no room was wanted: %v", stock.wanted)
+	}
+}
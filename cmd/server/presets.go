@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/vancomm/minesweeper-server/internal/config"
+	"github.com/vancomm/minesweeper-server/internal/mines"
+)
+
+/*
+presetRegistry holds the built-in presets followed by any the
+operator has added, in the order they are listed to clients.
+*/
+type presetRegistry struct {
+	presets []mines.Preset
+}
+
+func newPresetRegistry(custom []config.Preset, limits mines.Limits) (*presetRegistry, error) {
+	reg := &presetRegistry{presets: mines.DefaultPresets()}
+	for _, c := range custom {
+		if _, ok := reg.lookup(c.Name); ok {
+			return nil, fmt.Errorf(`duplicate preset "%s"`, c.Name)
+		}
+		params, err := mines.ParseGameSeed(c.Seed)
+		if err != nil {
+			return nil, fmt.Errorf(`preset "%s": %w`, c.Name, err)
+		}
+		if err := params.Validate(limits); err != nil {
+			return nil, fmt.Errorf(`preset "%s": %w`, c.Name, err)
+		}
+		reg.presets = append(reg.presets, mines.Preset{Name: c.Name, GameParams: *params})
+	}
+	return reg, nil
+}
+
+func (reg *presetRegistry) lookup(name string) (mines.GameParams, bool) {
+	for _, p := range reg.presets {
+		if p.Name == name {
+			return p.GameParams, true
+		}
+	}
+	return mines.GameParams{}, false
+}
+
+type presetDTO struct {
+	Name         string `json:"name"`
+	Seed         string `json:"seed"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	MineCount    int    `json:"mine_count"`
+	Unique       bool   `json:"unique"`
+	Tiling       string `json:"tiling"`
+	Wrap         bool   `json:"wrap"`
+	MinesPerCell int    `json:"mines_per_cell"`
+	MaxGrade     string `json:"max_grade"`
+	FirstClick   string `json:"first_click"`
+	Lives        int    `json:"lives"`
+	TimeLimit    int    `json:"time_limit"`
+}
+
+func (app application) handleFetchPresets(w http.ResponseWriter, r *http.Request) {
+	dtos := make([]presetDTO, 0, len(app.presets.presets))
+	for _, p := range app.presets.presets {
+		dtos = append(dtos, presetDTO{
+			Name:         p.Name,
+			Seed:         p.Seed(),
+			Width:        p.Width,
+			Height:       p.Height,
+			MineCount:    p.MineCount,
+			Unique:       p.Unique,
+			Tiling:       p.Tiling.String(),
+			Wrap:         p.Wrap,
+			MinesPerCell: p.PerCell(),
+			MaxGrade:     p.MaxGrade.String(),
+			FirstClick:   p.FirstClick.String(),
+			Lives:        p.Lives,
+			TimeLimit:    p.TimeLimit,
+		})
+	}
+	app.replyWithJSON(w, dtos)
+}
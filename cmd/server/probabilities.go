@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vancomm/minesweeper-server/internal/mines"
+)
+
+type probabilitiesDTO struct {
+	Move          int        `json:"move"`
+	Width         int        `json:"width"`
+	Height        int        `json:"height"`
+	Grid          mines.Grid `json:"grid"`
+	Probabilities []float64  `json:"probabilities"`
+}
+
+/*
+Mine probabilities are only served for finished games, for the
+position after the given number of moves. By default that is the
+position the final move was made from.
+*/
+func (app application) handleProbabilities(w http.ResponseWriter, r *http.Request) {
+	sessionId, err := app.getSessionId(r)
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	session, err := app.repo.FetchGameSession(r.Context(), sessionId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			app.notFound(w)
+		} else {
+			app.internalError(w, "could not fetch session from db", slog.Any("error", err))
+		}
+		return
+	}
+
+	playerId, ok := app.getAuthenticatedPlayerId(r)
+	if ok && session.PlayerId != nil && *session.PlayerId != playerId {
+		app.unauthorized(w)
+		return
+	}
+
+	game, err := mines.DecodeGameState(session.State)
+	if err != nil {
+		app.internalError(w, "db returned invalid game_session.state", slog.Any("error", err))
+		return
+	}
+
+	if !game.Finished() {
+		w.WriteHeader(http.StatusConflict)
+		app.replyWithJSON(w, map[string]string{"error": "game is not over"})
+		return
+	}
+
+	move := max(game.HistoryPos-1, 0)
+	if query := r.URL.Query(); query.Has("move") {
+		move, err = strconv.Atoi(query.Get("move"))
+		if err != nil {
+			app.badRequest(w)
+			return
+		}
+	}
+
+	position, ok := game.Position(move)
+	if !ok {
+		app.notFound(w)
+		return
+	}
+
+	probs, err := position.MineProbabilities()
+	if errors.Is(err, mines.ErrProbabilitiesMultiMine) {
+		w.WriteHeader(http.StatusConflict)
+		app.replyWithJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, mines.ErrProbabilitiesTooComplex) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		app.replyWithJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		app.internalError(w, "failed to compute mine probabilities", slog.Any("error", err))
+		return
+	}
+
+	app.replyWithJSON(w, probabilitiesDTO{
+		Move:          move,
+		Width:         position.Width,
+		Height:        position.Height,
+		Grid:          position.PlayerGrid,
+		Probabilities: probs,
+	})
+}
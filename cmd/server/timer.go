@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vancomm/minesweeper-server/internal/mines"
+	"github.com/vancomm/minesweeper-server/internal/repository"
+)
+
+/*
+gameTimers keeps the clock for timed games. Each timed session that
+is still being played has a timer, which ends the game as lost when
+its time runs out and then wakes anything watching the session, so
+that a websocket nobody is typing into can still tell its client.
+
+Timers last only as long as the server does, so every handler that
+makes a move checks the deadline for itself as well (see [timeUp]).
+The timers only make sure that nobody has to make a move to find
+out that the time is up.
+*/
+type gameTimers struct {
+	logger *slog.Logger
+	repo   *repository.Queries
+
+	mu     sync.Mutex
+	timers map[int]*gameTimer
+}
+
+type gameTimer struct {
+	timer *time.Timer
+	done  chan struct{} /* closed once the game has been ended */
+}
+
+func newGameTimers(logger *slog.Logger, repo *repository.Queries) *gameTimers {
+	return &gameTimers{
+		logger: logger,
+		repo:   repo,
+		timers: make(map[int]*gameTimer),
+	}
+}
+
+/*
+Return when a session's time runs out, if it has a time limit.
+*/
+func sessionDeadline(session *repository.GameSession) (time.Time, bool) {
+	if session.TimeLimit <= 0 {
+		return time.Time{}, false
+	}
+	return session.StartedAt.Time.Add(time.Duration(session.TimeLimit) * time.Second), true
+}
+
+/*
+If the game isn't finished but its time ran out before now, end it
+as lost, as of its deadline, and report true. The caller saves it.
+A loss that could have been undone until then stays one that ended
+when the mine went off.
+*/
+func timeUp(session *repository.GameSession, game *mines.GameState, now time.Time) bool {
+	deadline, ok := sessionDeadline(session)
+	if !ok || game.Finished() || now.Before(deadline) {
+		return false
+	}
+	if !game.Dead {
+		session.EndedAt.Time = deadline
+	}
+	game.RevealPlayerGrid()
+	return true
+}
+
+/*
+watch starts the timer for a session, unless it is running already,
+and returns a channel that is closed once the time is up and the
+game has been ended. Sessions without a time limit, or that are
+over, have nothing to watch, and get a nil channel.
+*/
+func (t *gameTimers) watch(session *repository.GameSession) <-chan struct{} {
+	deadline, ok := sessionDeadline(session)
+	if !ok || session.Dead || session.Won {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if gt, ok := t.timers[session.GameSessionId]; ok {
+		return gt.done
+	}
+	gt := &gameTimer{done: make(chan struct{})}
+	id := session.GameSessionId
+	gt.timer = time.AfterFunc(time.Until(deadline), func() { t.fire(id, gt) })
+	t.timers[id] = gt
+	return gt.done
+}
+
+/*
+stop drops the timer of a session that ended before its time ran
+out. Anything still watching it is never woken.
+*/
+func (t *gameTimers) stop(sessionId int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if gt, ok := t.timers[sessionId]; ok && gt.timer.Stop() {
+		delete(t.timers, sessionId)
+	}
+}
+
+func (t *gameTimers) fire(sessionId int, gt *gameTimer) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := t.expire(ctx, sessionId); err != nil {
+		t.logger.Error(
+			"unable to end timed game",
+			slog.Int("game_session_id", sessionId), slog.Any("error", err),
+		)
+	}
+
+	t.mu.Lock()
+	delete(t.timers, sessionId)
+	t.mu.Unlock()
+	close(gt.done)
+}
+
+/*
+End a timed game as lost, unless it was over before its time ran
+out.
+*/
+func (t *gameTimers) expire(ctx context.Context, sessionId int) error {
+	session, err := t.repo.FetchGameSession(ctx, sessionId)
+	if err != nil {
+		return fmt.Errorf("could not fetch session from db: %w", err)
+	}
+	game, err := mines.DecodeGameState(session.State)
+	if err != nil {
+		return fmt.Errorf("db returned invalid game_session.state: %w", err)
+	}
+	if !timeUp(session, game, time.Now()) {
+		return nil
+	}
+
+	b, err := game.Bytes()
+	if err != nil {
+		return fmt.Errorf("unable to serialize game state: %w", err)
+	}
+	_, err = t.repo.ExpireGameSession(
+		ctx,
+		session.GameSessionId,
+		repository.UpdateGameSessionParams{
+			Dead:    &game.Dead,
+			Won:     &game.Won,
+			EndedAt: &session.EndedAt.Time,
+			State:   &b,
+		},
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		/* A move ended the game while we were at it. */
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to update session in db: %w", err)
+	}
+	t.logger.Debug("time up", slog.Int("game_session_id", sessionId))
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vancomm/minesweeper-server/internal/mines"
+	"github.com/vancomm/minesweeper-server/internal/repository"
+)
+
+func (app application) handleUndo(w http.ResponseWriter, r *http.Request) {
+	app.handleHistoryStep(w, r, (*mines.GameState).Undo, "nothing to undo")
+}
+
+func (app application) handleRedo(w http.ResponseWriter, r *http.Request) {
+	app.handleHistoryStep(w, r, (*mines.GameState).Redo, "nothing to redo")
+}
+
+func (app application) handleHistoryStep(
+	w http.ResponseWriter, r *http.Request,
+	step func(*mines.GameState) bool, conflict string,
+) {
+	sessionId, err := app.getSessionId(r)
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	session, err := app.repo.FetchGameSession(r.Context(), sessionId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			app.notFound(w)
+		} else {
+			app.internalError(w, "could not fetch session from db", slog.Any("error", err))
+		}
+		return
+	}
+
+	playerId, ok := app.getAuthenticatedPlayerId(r)
+	if ok && session.PlayerId != nil && *session.PlayerId != playerId {
+		app.unauthorized(w)
+		return
+	}
+
+	game, err := mines.DecodeGameState(session.State)
+	if err != nil {
+		app.internalError(w, "db returned invalid game_session.state", slog.Any("error", err))
+		return
+	}
+
+	if timeUp(session, game, time.Now()) {
+		/* There's no taking anything back once the time is up. */
+	} else if !step(game) {
+		w.WriteHeader(http.StatusConflict)
+		app.replyWithJSON(w, map[string]string{"error": conflict})
+		return
+	}
+
+	if game.Won || game.Dead {
+		if game.Finished() {
+			game.RevealPlayerGrid()
+		}
+		if session.EndedAt.Time.IsZero() {
+			session.EndedAt.Time = time.Now().UTC()
+		}
+	} else {
+		session.EndedAt.Time = time.Time{}
+	}
+
+	b, err := game.Bytes()
+	if err != nil {
+		app.internalError(w, "unable to serialize game state", slog.Any("error", err))
+		return
+	}
+
+	session, err = app.repo.UpdateGameSession(
+		r.Context(),
+		session.GameSessionId,
+		repository.UpdateGameSessionParams{
+			Dead:     &game.Dead,
+			Won:      &game.Won,
+			EndedAt:  &session.EndedAt.Time,
+			State:    &b,
+			UsedUndo: &game.UsedUndo,
+		},
+	)
+	if err != nil {
+		app.internalError(w, "unable to update session in db", slog.Any("error", err))
+		return
+	}
+
+	/* Taking back a fatal click puts a timed game back on the clock. */
+	app.timers.watch(session)
+
+	dto, err := NewGameSessionDTO(*session)
+	if err != nil {
+		app.internalError(w, "failed to create game session dto", slog.Any("error", err))
+		return
+	}
+
+	app.replyWithJSON(w, dto)
+}
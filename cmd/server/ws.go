@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -18,19 +19,25 @@ import (
 type wsCommand string
 
 const (
-	wsNoop  wsCommand = "g"
-	wsOpen  wsCommand = "o"
-	wsFlag  wsCommand = "f"
-	wsChord wsCommand = "c"
+	wsNoop     wsCommand = "g"
+	wsOpen     wsCommand = "o"
+	wsFlag     wsCommand = "f"
+	wsChord    wsCommand = "c"
+	wsQuestion wsCommand = "q"
+	wsUndo     wsCommand = "u"
+	wsRedo     wsCommand = "r"
+	wsHint     wsCommand = "h"
+	wsSolve    wsCommand = "s"
 )
 
 type gameExecutor struct {
 	*application
 	*mines.GameState
+	hint *hintDTO /* reply to the last hint command, if any */
 }
 
 func newGameExecutor(app *application, state *mines.GameState) *gameExecutor {
-	return &gameExecutor{app, state}
+	return &gameExecutor{application: app, GameState: state}
 }
 
 func (game gameExecutor) openCell(args []string) error {
@@ -41,8 +48,7 @@ func (game gameExecutor) openCell(args []string) error {
 	if !game.PointInBounds(x, y) {
 		return fmt.Errorf("invalid square coordinates")
 	}
-	game.OpenCell(x, y)
-	return nil
+	return game.Do(mines.Move{Kind: mines.MoveOpen, X: x, Y: y})
 }
 
 func (game gameExecutor) flagCell(args []string) error {
@@ -53,8 +59,18 @@ func (game gameExecutor) flagCell(args []string) error {
 	if !game.PointInBounds(x, y) {
 		return fmt.Errorf("invalid square coordinates")
 	}
-	game.FlagCell(x, y)
-	return nil
+	return game.Do(mines.Move{Kind: mines.MoveFlag, X: x, Y: y})
+}
+
+func (game gameExecutor) questionCell(args []string) error {
+	x, y, err := parseXY(args)
+	if err != nil {
+		return err
+	}
+	if !game.PointInBounds(x, y) {
+		return fmt.Errorf("invalid square coordinates")
+	}
+	return game.Do(mines.Move{Kind: mines.MoveQuestion, X: x, Y: y})
 }
 
 func (game gameExecutor) chordCell(args []string) error {
@@ -65,11 +81,24 @@ func (game gameExecutor) chordCell(args []string) error {
 	if !game.PointInBounds(x, y) {
 		return fmt.Errorf("invalid square coordinates")
 	}
-	game.ChordCell(x, y)
+	return game.Do(mines.Move{Kind: mines.MoveChord, X: x, Y: y})
+}
+
+func (game *gameExecutor) requestHint() error {
+	hint, err := game.Hint()
+	if errors.Is(err, mines.ErrGameOver) {
+		/* As with moves on a finished game, there is nothing to send. */
+		game.hint = nil
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("solver failed on player grid: %w", err)
+	}
+	game.hint = NewHintDTO(hint)
 	return nil
 }
 
-func (game gameExecutor) execute(query string) error {
+func (game *gameExecutor) execute(query string) error {
 	tokens := strings.Split(query, " ")
 	cmd, args := wsCommand(tokens[0]), tokens[1:]
 	switch cmd {
@@ -81,36 +110,117 @@ func (game gameExecutor) execute(query string) error {
 		return game.flagCell(args)
 	case wsChord:
 		return game.chordCell(args)
+	case wsQuestion:
+		return game.questionCell(args)
+	case wsUndo:
+		game.Undo()
+		return nil
+	case wsRedo:
+		game.Redo()
+		return nil
+	case wsHint:
+		return game.requestHint()
+	case wsSolve:
+		return game.Solve()
 	default:
 		return fmt.Errorf("unknown command '%s', args: %v", cmd, args)
 	}
 }
 
-func (game gameExecutor) wsRunGameLoop(
+/*
+Read messages off the connection in the background, so that the game
+loop can wait for the clock as well as the client.
+*/
+func wsReadMessages(conn *websocket.Conn, done <-chan struct{}) (<-chan []byte, <-chan error) {
+	messages := make(chan []byte)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			mt, msgBuf, err := conn.ReadMessage()
+			if err != nil {
+				errs <- err
+				return
+			}
+			if mt != websocket.TextMessage {
+				errs <- nil
+				return
+			}
+			select {
+			case messages <- msgBuf:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return messages, errs
+}
+
+func (game *gameExecutor) wsRunGameLoop(
 	ctx context.Context, conn *websocket.Conn, session *repository.GameSession,
 ) error {
+	done := make(chan struct{})
+	defer close(done)
+	messages, readErrs := wsReadMessages(conn, done)
+	expired := game.timers.watch(session)
+
 	for {
-		mt, msgBuf, err := conn.ReadMessage()
-		if err != nil {
+		var msgBuf []byte
+		select {
+		case err := <-readErrs:
 			return err
-		}
-		if mt != websocket.TextMessage {
-			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-expired:
+			/*
+			 * The time ran out while the client was idle. The
+			 * timer has already ended the game, so push the lost
+			 * game to the client unasked.
+			 */
+			expired = nil
+			var err error
+			session, err = game.repo.FetchGameSession(ctx, session.GameSessionId)
+			if err != nil {
+				return fmt.Errorf("could not fetch session from db: %w", err)
+			}
+			state, err := mines.DecodeGameState(session.State)
+			if err != nil {
+				return fmt.Errorf("db returned invalid game_session.state: %w", err)
+			}
+			game.GameState = state
+
+			dto, err := NewGameSessionDTO(*session)
+			if err != nil {
+				return fmt.Errorf("failed to create game session dto: %w", err)
+			}
+			if err := conn.WriteJSON(dto); err != nil {
+				return fmt.Errorf("unable to write json: %w", err)
+			}
+			continue
+		case msgBuf = <-messages:
 		}
 
 		message := strings.TrimSpace(string(msgBuf))
 		lines := strings.Split(message, "\n")
 	LINES:
 		for _, line := range lines {
+			if timeUp(session, game.GameState, time.Now()) {
+				break LINES
+			}
 			err := game.execute(strings.TrimSpace(line))
 			if err != nil {
 				return err
 			}
 			if game.Won || game.Dead {
-				session.EndedAt.Time = time.Now().UTC()
-				game.RevealPlayerGrid()
+				if session.EndedAt.Time.IsZero() {
+					session.EndedAt.Time = time.Now().UTC()
+				}
+				if game.Finished() {
+					game.RevealPlayerGrid()
+				}
+				game.timers.stop(session.GameSessionId)
 				break LINES
 			}
+			session.EndedAt.Time = time.Time{}
 		}
 
 		stateBuf, err := game.Bytes()
@@ -122,19 +232,29 @@ func (game gameExecutor) wsRunGameLoop(
 			ctx,
 			session.GameSessionId,
 			repository.UpdateGameSessionParams{
-				Dead:    &game.Dead,
-				Won:     &game.Won,
-				EndedAt: &session.EndedAt.Time,
-				State:   &stateBuf,
+				Dead:      &game.Dead,
+				Won:       &game.Won,
+				EndedAt:   &session.EndedAt.Time,
+				State:     &stateBuf,
+				UsedUndo:  &game.UsedUndo,
+				UsedSolve: &game.UsedSolve,
+				Clicks:    &game.Clicks,
 			})
 		if err != nil {
 			return fmt.Errorf("unable to update session in db: %w", err)
 		}
 
+		/*
+		 * The game may be back on the clock after an undo, or off
+		 * it after a move that ended it.
+		 */
+		expired = game.timers.watch(session)
+
 		dto, err := NewGameSessionDTO(*session)
 		if err != nil {
 			return fmt.Errorf("failed to create game session dto: %w", err)
 		}
+		dto.Hint, game.hint = game.hint, nil
 
 		if err := conn.WriteJSON(dto); err != nil {
 			return fmt.Errorf("unable to write json: %w", err)
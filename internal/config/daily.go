@@ -0,0 +1,18 @@
+package config
+
+import "os"
+
+type Daily struct {
+	/*
+	 * Mixed into the seed of every daily board, so that boards
+	 * can't be generated ahead of time by anyone with the source.
+	 */
+	Secret string
+}
+
+func NewDaily() (*Daily, error) {
+	daily := &Daily{
+		Secret: os.Getenv("DAILY_SECRET"),
+	}
+	return daily, nil
+}
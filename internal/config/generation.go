@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+type Generation struct {
+	Timeout     time.Duration
+	MaxAttempts int /* 0 means only the timeout applies */
+}
+
+func NewGeneration() (*Generation, error) {
+	generation := &Generation{
+		Timeout: time.Second * 5,
+	}
+
+	if s, ok := os.LookupEnv("GAME_GENERATE_TIMEOUT"); ok {
+		timeout, err := time.ParseDuration(s)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("GAME_GENERATE_TIMEOUT must be a positive duration")
+		}
+		generation.Timeout = timeout
+	}
+
+	if s, ok := os.LookupEnv("GAME_GENERATE_MAX_ATTEMPTS"); ok {
+		maxAttempts, err := strconv.Atoi(s)
+		if err != nil || maxAttempts < 0 {
+			return nil, fmt.Errorf("GAME_GENERATE_MAX_ATTEMPTS must be a non-negative integer")
+		}
+		generation.MaxAttempts = maxAttempts
+	}
+
+	return generation, nil
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+type Limits struct {
+	MaxWidth   int
+	MaxHeight  int
+	MaxSquares int
+}
+
+func lookupLimit(key string, fallback int) (int, error) {
+	s, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s must be a positive integer", key)
+	}
+	return n, nil
+}
+
+func NewLimits() (*Limits, error) {
+	maxWidth, err := lookupLimit("GAME_MAX_WIDTH", 100)
+	if err != nil {
+		return nil, err
+	}
+
+	maxHeight, err := lookupLimit("GAME_MAX_HEIGHT", 100)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSquares, err := lookupLimit("GAME_MAX_SQUARES", 2500)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := &Limits{
+		MaxWidth:   maxWidth,
+		MaxHeight:  maxHeight,
+		MaxSquares: maxSquares,
+	}
+
+	return limits, nil
+}
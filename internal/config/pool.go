@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type Pool struct {
+	Presets []string /* names of the presets to keep boards in stock for */
+	Size    int      /* boards kept for each preset */
+	Workers int
+}
+
+func NewPool() (*Pool, error) {
+	pool := &Pool{
+		Presets: []string{"intermediate", "expert"},
+		Size:    16,
+		Workers: 1,
+	}
+
+	if s, ok := os.LookupEnv("POOL_PRESETS"); ok {
+		pool.Presets = nil
+		for _, name := range strings.Split(s, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				pool.Presets = append(pool.Presets, name)
+			}
+		}
+	}
+
+	if s, ok := os.LookupEnv("POOL_SIZE"); ok {
+		size, err := strconv.Atoi(s)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("POOL_SIZE must be a non-negative integer")
+		}
+		pool.Size = size
+	}
+
+	if s, ok := os.LookupEnv("POOL_WORKERS"); ok {
+		workers, err := strconv.Atoi(s)
+		if err != nil || workers < 1 {
+			return nil, fmt.Errorf("POOL_WORKERS must be a positive integer")
+		}
+		pool.Workers = workers
+	}
+
+	return pool, nil
+}
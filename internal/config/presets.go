@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+type Preset struct {
+	Name string
+	Seed string
+}
+
+/*
+NewPresets reads the operator's own presets from GAME_PRESETS, a
+comma-separated list of name=seed pairs, e.g.
+"huge=50:50:500:1,honeycomb=16:16:40:1:hex".
+*/
+func NewPresets() ([]Preset, error) {
+	s, ok := os.LookupEnv("GAME_PRESETS")
+	if !ok || s == "" {
+		return nil, nil
+	}
+
+	var presets []Preset
+	for _, pair := range strings.Split(s, ",") {
+		name, seed, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" || seed == "" {
+			return nil, fmt.Errorf(`malformed GAME_PRESETS entry "%s"`, pair)
+		}
+		presets = append(presets, Preset{Name: name, Seed: seed})
+	}
+
+	return presets, nil
+}
@@ -0,0 +1,451 @@
+package mines
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+/*
+Game states are saved in a compact binary form, which starts with
+the version of the format it is in. Version 5 is
+
+	version            byte
+	flags              uvarint, one bit for each of dead, won, used
+	                   solve, unique, wrap, custom layout, question
+	                   marks, used undo and having a history base
+	width, height      uvarints
+	mine count         uvarint
+	mines per cell     uvarint
+	tiling             uvarint
+	max grade          uvarint
+	first click        uvarint
+	lives              uvarint
+	time limit         uvarint
+	start x, y         varints
+	grid               the number of mines in each square
+	player grid        each square's state, coded by cellCode
+	history base       the same, if there is one
+	history length     uvarint
+	history            kind, x and y of each move, as uvarints
+	history position   uvarint
+	clicks             left, right, chord and wasted, as uvarints
+
+Each grid is packed in as few bits a square as its values need,
+most significant bit first, and padded out to a whole byte with
+zero bits.
+
+Each older version is the same with one field fewer: version 4
+has no time limit, version 3 no lives either, version 2 no first
+click policy, and version 1 no max grade.
+
+States saved before there was a version are gob encodings of the
+GameState struct. A gob stream begins with the length of the
+message describing the struct's type, which is far too long to fit
+in the one byte that would make it look like a version.
+*/
+const StateVersion byte = 5
+
+var ErrInvalidState = errors.New("invalid game state")
+
+const (
+	stateDead = 1 << iota
+	stateWon
+	stateUsedSolve
+	stateUnique
+	stateWrap
+	stateCustomLayout
+	stateQuestionMarks
+	stateUsedUndo
+	stateHistoryBase
+)
+
+/*
+Code a square's state as a small number: covered states first,
+then the states shown when the game is over, then open squares by
+their number.
+*/
+const (
+	codeUnknown  = 0
+	codeQuestion = 1
+	codeFlag     = 1 /* plus the number of mines flagged */
+	codeRevealed = 2 + MaxMinesPerCell
+	codeOpen     = codeRevealed + 4
+)
+
+func cellCode(c CellState) (uint64, bool) {
+	switch {
+	case c == Unknown:
+		return codeUnknown, true
+	case c == Question:
+		return codeQuestion, true
+	case c.IsFlag():
+		return uint64(codeFlag + c.FlagCount()), true
+	case CorrectlyFlagged <= c && c <= UnflaggedMine:
+		return uint64(codeRevealed + c - CorrectlyFlagged), true
+	case c.IsOpen():
+		return uint64(codeOpen + c), true
+	default:
+		return 0, false
+	}
+}
+
+func cellFromCode(code uint64) CellState {
+	switch {
+	case code == codeUnknown:
+		return Unknown
+	case code == codeQuestion:
+		return Question
+	case code < codeRevealed:
+		return FlagOf(int(code - codeFlag))
+	case code < codeOpen:
+		return CorrectlyFlagged + CellState(code-codeRevealed)
+	default:
+		return CellState(code - codeOpen)
+	}
+}
+
+/*
+Return the number of bits a square of the grid and of the player
+grid is packed into.
+*/
+func (p GameParams) packedBits() (grid, player int) {
+	perCell := p.PerCell()
+	return bits.Len(uint(perCell)), bits.Len(uint(codeOpen + 8*perCell))
+}
+
+func appendPacked(buf []byte, n, width int, value func(i int) uint64) []byte {
+	start := len(buf)
+	buf = append(buf, make([]byte, (n*width+7)/8)...)
+	for i := range n {
+		v := value(i)
+		for b := range width {
+			if v>>(width-1-b)&1 != 0 {
+				pos := i*width + b
+				buf[start+pos/8] |= 0x80 >> (pos % 8)
+			}
+		}
+	}
+	return buf
+}
+
+func (g GameState) Bytes() ([]byte, error) {
+	n := g.Width * g.Height
+	if len(g.Grid) != n || len(g.PlayerGrid) != n ||
+		g.HistoryBase != nil && len(g.HistoryBase) != n {
+		return nil, fmt.Errorf("%w: grids do not match the board", ErrInvalidState)
+	}
+	for _, grid := range []Grid{g.PlayerGrid, g.HistoryBase} {
+		for _, c := range grid {
+			if _, ok := cellCode(c); !ok {
+				return nil, fmt.Errorf("%w: cannot save square state %d", ErrInvalidState, c)
+			}
+		}
+	}
+
+	var flags uint64
+	for bit, set := range map[uint64]bool{
+		stateDead:          g.Dead,
+		stateWon:           g.Won,
+		stateUsedSolve:     g.UsedSolve,
+		stateUnique:        g.Unique,
+		stateWrap:          g.Wrap,
+		stateCustomLayout:  g.CustomLayout,
+		stateQuestionMarks: g.QuestionMarks,
+		stateUsedUndo:      g.UsedUndo,
+		stateHistoryBase:   g.HistoryBase != nil,
+	} {
+		if set {
+			flags |= bit
+		}
+	}
+
+	buf := []byte{StateVersion}
+	buf = binary.AppendUvarint(buf, flags)
+	buf = binary.AppendUvarint(buf, uint64(g.Width))
+	buf = binary.AppendUvarint(buf, uint64(g.Height))
+	buf = binary.AppendUvarint(buf, uint64(g.MineCount))
+	buf = binary.AppendUvarint(buf, uint64(g.MinesPerCell))
+	buf = binary.AppendUvarint(buf, uint64(g.Tiling))
+	buf = binary.AppendUvarint(buf, uint64(g.MaxGrade))
+	buf = binary.AppendUvarint(buf, uint64(g.FirstClick))
+	buf = binary.AppendUvarint(buf, uint64(g.Lives))
+	buf = binary.AppendUvarint(buf, uint64(g.TimeLimit))
+	buf = binary.AppendVarint(buf, int64(g.StartX))
+	buf = binary.AppendVarint(buf, int64(g.StartY))
+
+	gridBits, playerBits := g.packedBits()
+	buf = appendPacked(buf, n, gridBits, func(i int) uint64 { return uint64(g.Grid[i]) })
+	code := func(grid Grid) func(i int) uint64 {
+		return func(i int) uint64 {
+			c, _ := cellCode(grid[i])
+			return c
+		}
+	}
+	buf = appendPacked(buf, n, playerBits, code(g.PlayerGrid))
+	if g.HistoryBase != nil {
+		buf = appendPacked(buf, n, playerBits, code(g.HistoryBase))
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(g.History)))
+	for _, m := range g.History {
+		buf = binary.AppendUvarint(buf, uint64(m.Kind))
+		buf = binary.AppendUvarint(buf, uint64(m.X))
+		buf = binary.AppendUvarint(buf, uint64(m.Y))
+	}
+	buf = binary.AppendUvarint(buf, uint64(g.HistoryPos))
+
+	buf = binary.AppendUvarint(buf, uint64(g.Clicks.Left))
+	buf = binary.AppendUvarint(buf, uint64(g.Clicks.Right))
+	buf = binary.AppendUvarint(buf, uint64(g.Clicks.Chord))
+	buf = binary.AppendUvarint(buf, uint64(g.Clicks.Wasted))
+	return buf, nil
+}
+
+func DecodeGameState(buf []byte) (*GameState, error) {
+	if len(buf) > 0 && 1 <= buf[0] && buf[0] <= StateVersion {
+		return decodeVersionedGameState(buf[0], buf[1:])
+	}
+	return decodeGobGameState(buf)
+}
+
+/*
+Read from a saved state, remembering the first thing that went
+wrong so that it only has to be checked at the end.
+*/
+type stateReader struct {
+	buf []byte
+	err error
+}
+
+func (r *stateReader) fail(format string, args ...any) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: "+format, append([]any{ErrInvalidState}, args...)...)
+	}
+}
+
+func (r *stateReader) uvarint(max uint64) uint64 {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.fail("truncated")
+		return 0
+	}
+	r.buf = r.buf[n:]
+	if v > max {
+		r.fail("value %d out of range", v)
+		return 0
+	}
+	return v
+}
+
+func (r *stateReader) int(max int) int {
+	return int(r.uvarint(uint64(max)))
+}
+
+func (r *stateReader) varint() int {
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.fail("truncated")
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return int(v)
+}
+
+func (r *stateReader) packed(n, width int, set func(i int, v uint64)) {
+	size := (n*width + 7) / 8
+	if r.err != nil {
+		return
+	}
+	if size > len(r.buf) {
+		r.fail("truncated")
+		return
+	}
+	for i := range n {
+		var v uint64
+		for b := range width {
+			pos := i*width + b
+			v = v<<1 | uint64(r.buf[pos/8]>>(7-pos%8)&1)
+		}
+		set(i, v)
+	}
+	if pad := size*8 - n*width; pad > 0 && r.buf[size-1]&(1<<pad-1) != 0 {
+		r.fail("padding bits set")
+	}
+	r.buf = r.buf[size:]
+}
+
+func (r *stateReader) playerGrid(n, width, perCell int) Grid {
+	grid := make(Grid, n)
+	r.packed(n, width, func(i int, v uint64) {
+		c := cellFromCode(v)
+		if code, ok := cellCode(c); !ok || code != v ||
+			c.FlagCount() > perCell || c.IsOpen() && int(c) > 8*perCell {
+			r.fail("square state code %d out of range", v)
+		}
+		grid[i] = c
+	})
+	return grid
+}
+
+func decodeVersionedGameState(version byte, buf []byte) (*GameState, error) {
+	r := &stateReader{buf: buf}
+	s := &GameState{}
+
+	flags := r.uvarint(stateHistoryBase<<1 - 1)
+	s.Dead = flags&stateDead != 0
+	s.Won = flags&stateWon != 0
+	s.UsedSolve = flags&stateUsedSolve != 0
+	s.Unique = flags&stateUnique != 0
+	s.Wrap = flags&stateWrap != 0
+	s.CustomLayout = flags&stateCustomLayout != 0
+	s.QuestionMarks = flags&stateQuestionMarks != 0
+	s.UsedUndo = flags&stateUsedUndo != 0
+
+	/*
+	 * Every square takes at least a bit, so a board can't have
+	 * more squares than there are bits left to describe them.
+	 */
+	maxSquares := 8 * len(buf)
+	s.Width = r.int(maxSquares)
+	s.Height = r.int(maxSquares)
+	n := s.Width * s.Height
+	if r.err == nil && (n == 0 || n > maxSquares) {
+		r.fail("board is %dx%d", s.Width, s.Height)
+	}
+	s.MineCount = r.int(n * MaxMinesPerCell)
+	s.MinesPerCell = r.int(MaxMinesPerCell)
+	s.Tiling = Tiling(r.uvarint(uint64(Hex)))
+	if version >= 2 {
+		s.MaxGrade = Grade(r.uvarint(uint64(GradeHard)))
+	}
+	if version >= 3 {
+		s.FirstClick = FirstClick(r.uvarint(uint64(FirstClickChosen)))
+	}
+	if version >= 4 {
+		s.Lives = r.int(MaxLives)
+	}
+	if version >= 5 {
+		s.TimeLimit = r.int(MaxTimeLimit)
+	}
+	s.StartX, s.StartY = r.varint(), r.varint()
+	if r.err == nil && !s.PointInBounds(s.StartX, s.StartY) {
+		r.fail("start square %d,%d is off the board", s.StartX, s.StartY)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	gridBits, playerBits := s.packedBits()
+	perCell := s.PerCell()
+	mineCount := 0
+	s.Grid = make([]int8, n)
+	r.packed(n, gridBits, func(i int, v uint64) {
+		if v > uint64(perCell) {
+			r.fail("square holds %d mines", v)
+		}
+		s.Grid[i] = int8(v)
+		mineCount += int(v)
+	})
+	if r.err == nil && mineCount != s.MineCount {
+		r.fail("layout has %d mines, expected %d", mineCount, s.MineCount)
+	}
+	s.PlayerGrid = r.playerGrid(n, playerBits, perCell)
+	if flags&stateHistoryBase != 0 {
+		s.HistoryBase = r.playerGrid(n, playerBits, perCell)
+	}
+
+	/* A move takes at least three bytes. */
+	moves := r.int(len(r.buf) / 3)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if moves > 0 {
+		s.History = make([]Move, moves)
+	}
+	for i := range s.History {
+		s.History[i] = Move{
+			Kind: MoveKind(r.uvarint(uint64(MoveQuestion))),
+			X:    r.int(s.Width - 1),
+			Y:    r.int(s.Height - 1),
+		}
+		if r.err == nil && s.History[i].Kind == 0 {
+			r.fail("move %d has no kind", i)
+		}
+	}
+	s.HistoryPos = r.int(moves)
+
+	s.Clicks.Left = r.int(1 << 31)
+	s.Clicks.Right = r.int(1 << 31)
+	s.Clicks.Chord = r.int(1 << 31)
+	s.Clicks.Wasted = r.int(s.Clicks.Total())
+
+	if r.err == nil && len(r.buf) > 0 {
+		r.fail("%d bytes left over", len(r.buf))
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return s, nil
+}
+
+func decodeGobGameState(buf []byte) (*GameState, error) {
+	var game GameState
+	err := gob.NewDecoder(bytes.NewBuffer(buf)).Decode(&game)
+	if err != nil {
+		if legacy, lerr := decodeLegacyGameState(buf); lerr == nil {
+			return legacy, nil
+		}
+		return nil, err
+	}
+	return &game, err
+}
+
+/*
+Games saved before a square could hold more than one mine have
+their layout stored as one bool per square, which gob won't decode
+into counts.
+*/
+type legacyGameState struct {
+	Dead, Won, UsedSolve bool
+	Grid                 []bool
+	PlayerGrid           Grid
+	GameParams
+	StartX, StartY int
+	CustomLayout   bool
+	QuestionMarks  bool
+	UsedUndo       bool
+	HistoryBase    Grid
+	History        []Move
+	HistoryPos     int
+}
+
+func decodeLegacyGameState(buf []byte) (*GameState, error) {
+	var l legacyGameState
+	if err := gob.NewDecoder(bytes.NewBuffer(buf)).Decode(&l); err != nil {
+		return nil, err
+	}
+	grid := make([]int8, len(l.Grid))
+	for i, mine := range l.Grid {
+		if mine {
+			grid[i] = 1
+		}
+	}
+	return &GameState{
+		Dead: l.Dead, Won: l.Won, UsedSolve: l.UsedSolve,
+		Grid:          grid,
+		PlayerGrid:    l.PlayerGrid,
+		GameParams:    l.GameParams,
+		StartX:        l.StartX,
+		StartY:        l.StartY,
+		CustomLayout:  l.CustomLayout,
+		QuestionMarks: l.QuestionMarks,
+		UsedUndo:      l.UsedUndo,
+		HistoryBase:   l.HistoryBase,
+		History:       l.History,
+		HistoryPos:    l.HistoryPos,
+	}, nil
+}
@@ -0,0 +1,203 @@
+package mines
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+/*
+Games in the middle of being played on every kind of board, with
+undone moves, flags, question marks and a spent solve.
+*/
+func playedGames(t testing.TB) []*GameState {
+	tests := []GameParams{
+		{Width: 9, Height: 9, MineCount: 10, Unique: true},
+		{Width: 30, Height: 16, MineCount: 99},
+		{Width: 10, Height: 8, MineCount: 12, Unique: true, Tiling: Hex, Wrap: true},
+		{Width: 9, Height: 9, MineCount: 20, MinesPerCell: 3},
+		{Width: 16, Height: 16, MineCount: 40, Unique: true, MaxGrade: GradeMedium},
+		{Width: 9, Height: 9, MineCount: 10, Unique: true, FirstClick: FirstClickChosen},
+		{Width: 8, Height: 8, MineCount: 10, FirstClick: FirstClickSafe},
+		{Width: 9, Height: 9, MineCount: 20, Lives: 3, TimeLimit: 120},
+	}
+	var games []*GameState
+	r := rand.New(rand.NewPCG(1, 2))
+	for _, params := range tests {
+		game, err := NewGame(params, 4, 4, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		game.QuestionMarks = true
+		for range 10 {
+			x, y := r.IntN(params.Width), r.IntN(params.Height)
+			kind := []MoveKind{MoveOpen, MoveFlag, MoveChord, MoveQuestion}[r.IntN(4)]
+			game.Do(Move{Kind: kind, X: x, Y: y})
+			games = append(games, game.Clone())
+		}
+		game.Undo()
+		games = append(games, game.Clone())
+		game.Solve()
+		games = append(games, game.Clone())
+		game.RevealPlayerGrid()
+		games = append(games, game.Clone())
+	}
+	return games
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	for _, game := range playedGames(t) {
+		buf, err := game.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeGameState(buf)
+		if err != nil {
+			t.Fatalf("%s: %v", game.Seed(), err)
+		}
+		game.cover = coverCount{}
+		if !reflect.DeepEqual(decoded, game) {
+			t.Fatalf("%s: expected\n%+v\ngot\n%+v", game.Seed(), game, decoded)
+		}
+	}
+}
+
+func TestStateSmallerThanGob(t *testing.T) {
+	for _, game := range playedGames(t) {
+		buf, err := game.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var gobBuf bytes.Buffer
+		if err := gob.NewEncoder(&gobBuf).Encode(game); err != nil {
+			t.Fatal(err)
+		}
+		if 2*len(buf) > gobBuf.Len() {
+			t.Errorf("%s: %d bytes, gob takes %d", game.Seed(), len(buf), gobBuf.Len())
+		}
+	}
+}
+
+func TestDecodeGobState(t *testing.T) {
+	game := playedGames(t)[5]
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(game); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeGameState(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.cover = coverCount{}
+	if !reflect.DeepEqual(decoded, game) {
+		t.Fatalf("expected\n%+v\ngot\n%+v", game, decoded)
+	}
+}
+
+func TestDecodeOlderStates(t *testing.T) {
+	for _, game := range playedGames(t) {
+		buf, err := game.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		/*
+		 * Older versions are the same up to the tiling. Each
+		 * version after the first added one field after it: the
+		 * max grade, the first click policy, the lives and the
+		 * time limit.
+		 */
+		_, n := binary.Uvarint(buf[1:])
+		tilingEnd := 1 + n
+		for range 5 {
+			_, n = binary.Uvarint(buf[tilingEnd:])
+			tilingEnd += n
+		}
+		fieldEnds := []int{tilingEnd}
+		for range StateVersion - 1 {
+			_, n = binary.Uvarint(buf[fieldEnds[len(fieldEnds)-1]:])
+			fieldEnds = append(fieldEnds, fieldEnds[len(fieldEnds)-1]+n)
+		}
+
+		game.cover = coverCount{}
+		for version := StateVersion - 1; version >= 1; version-- {
+			old := append([]byte{version}, buf[1:fieldEnds[version-1]]...)
+			old = append(old, buf[fieldEnds[len(fieldEnds)-1]:]...)
+
+			decoded, err := DecodeGameState(old)
+			if err != nil {
+				t.Fatalf("%s: version %d: %v", game.Seed(), version, err)
+			}
+			want := *game
+			want.TimeLimit = 0 /* no older version has room for it */
+			if version < 4 {
+				want.Lives = 0
+			}
+			if version < 3 {
+				want.FirstClick = FirstClickOpening
+			}
+			if version < 2 {
+				want.MaxGrade = GradeAny
+			}
+			if !reflect.DeepEqual(decoded, &want) {
+				t.Fatalf("%s: version %d: expected\n%+v\ngot\n%+v", game.Seed(), version, &want, decoded)
+			}
+		}
+	}
+}
+
+func TestDecodeInvalidState(t *testing.T) {
+	game := playedGames(t)[0]
+	buf, err := game.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range [][]byte{
+		{StateVersion},
+		buf[:len(buf)-1],
+		append(buf, 0),
+	} {
+		if _, err := DecodeGameState(bad); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("%x: expected %v, got %v", bad, ErrInvalidState, err)
+		}
+	}
+
+	game.PlayerGrid[0] = Todo
+	if _, err := game.Bytes(); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("saved a square that was still to do: %v", err)
+	}
+}
+
+/*
+Anything the decoder accepts must save back to exactly the same
+bytes, so that nothing it lets through is lost or made up.
+*/
+func FuzzDecodeGameState(f *testing.F) {
+	for _, game := range playedGames(f) {
+		buf, err := game.Bytes()
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf)
+	}
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		if len(buf) == 0 || buf[0] != StateVersion {
+			return /* not ours; gob checks its own input */
+		}
+		game, err := DecodeGameState(buf)
+		if err != nil {
+			return
+		}
+		again, err := game.Bytes()
+		if err != nil {
+			t.Fatalf("decoded state can't be saved: %v", err)
+		}
+		if !bytes.Equal(again, buf) {
+			t.Fatalf("decoded %x but saved %x", buf, again)
+		}
+	})
+}
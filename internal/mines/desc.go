@@ -0,0 +1,272 @@
+// source: https://git.tartarus.org/simon/puzzles.git/mines.c
+
+package mines
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/*
+Obfuscate or de-obfuscate a bitmap of the given number of bits, as
+misc.c's obfuscate_bitmap does, so that a game description doesn't
+give the mine layout away at a glance.
+
+The algorithm is similar in concept to OAEP: the byte stream is
+split in half (rounding the half-way point down), a mask generated
+from the second half is XORed over the first, and then a mask
+generated from the (encoded) first half is XORed over the second.
+Decoding does the same two steps in reverse order. A mask is the
+concatenation of the SHA-1 hashes of its seed followed by each of
+the decimal integers from 0 upwards.
+*/
+func obfuscateBitmap(bmp []byte, bits int, decode bool) {
+	nbytes := (bits + 7) / 8
+	firsthalf := nbytes / 2
+
+	type step struct {
+		seed, target []byte
+	}
+	steps := [2]step{
+		{seed: bmp[firsthalf:nbytes], target: bmp[:firsthalf]},
+		{seed: bmp[:firsthalf], target: bmp[firsthalf:nbytes]},
+	}
+	if decode {
+		steps[0], steps[1] = steps[1], steps[0]
+	}
+
+	for _, st := range steps {
+		seed := append([]byte(nil), st.seed...)
+		var digest [sha1.Size]byte
+		digestpos, counter := sha1.Size, 0
+		for j := range st.target {
+			if digestpos >= sha1.Size {
+				digest = sha1.Sum(append(seed[:len(seed):len(seed)], strconv.Itoa(counter)...))
+				counter++
+				digestpos = 0
+			}
+			st.target[j] ^= digest[digestpos]
+			digestpos++
+		}
+
+		/*
+		 * Mask off the pad bits in the final byte after both steps.
+		 */
+		if bits%8 != 0 {
+			bmp[bits/8] &= byte(uint16(0xFF00) >> (bits % 8))
+		}
+	}
+}
+
+/*
+Encode a mine layout, along with the square the player starts
+from, as a game description: "x,y," followed by `m' for a masked
+(obfuscated) layout or `u' for a plain one, followed by the layout
+bitmap in hex.
+
+A layout with more than one mine in a square can't be written as a
+bitmap. It is written, unmasked, as `c' followed by the number of
+mines in each square, one digit per square.
+*/
+func describeLayout(grid []int8, x, y int, obfuscate bool) string {
+	if slices.Max(grid) > 1 {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%d,%d,c", x, y)
+		for _, n := range grid {
+			b.WriteByte('0' + byte(n))
+		}
+		return b.String()
+	}
+
+	area := len(grid)
+	bmp := make([]byte, (area+7)/8)
+	for i, n := range grid {
+		if n > 0 {
+			bmp[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	if obfuscate {
+		obfuscateBitmap(bmp, area, false)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d,%d,", x, y)
+	if obfuscate {
+		b.WriteByte('m') /* masked */
+	} else {
+		b.WriteByte('u')
+	}
+
+	/*
+	 * We can work to nibble rather than byte granularity, since
+	 * the obfuscation function guarantees to return a bit string
+	 * of the same length as its input.
+	 */
+	const hex = "0123456789abcdef"
+	for i := range (area + 3) / 4 {
+		v := bmp[i/2]
+		if i%2 == 0 {
+			v >>= 4
+		}
+		b.WriteByte(hex[v&0xF])
+	}
+	return b.String()
+}
+
+/*
+Description returns the game description of this game's layout in
+its obfuscated form, suitable for sharing.
+*/
+func (s *GameState) Description() string {
+	return describeLayout(s.Grid, s.StartX, s.StartY, true)
+}
+
+/*
+Parse the leading non-negative integer of desc, returning it along
+with the rest of the string, or -1 if it is too large to parse.
+*/
+func atoiPrefix(desc string) (int, string) {
+	n := 0
+	for n < len(desc) && '0' <= desc[n] && desc[n] <= '9' {
+		n++
+	}
+	v, err := strconv.Atoi(desc[:n])
+	if err != nil {
+		v = -1
+	}
+	return v, desc[n:]
+}
+
+func isDigitPrefix(desc string) bool {
+	return desc != "" && '0' <= desc[0] && desc[0] <= '9'
+}
+
+/*
+ValidateDesc checks that a game description is well-formed for
+these parameters. It is a port of validate_desc.
+*/
+func (p GameParams) ValidateDesc(desc string) error {
+	_, _, _, err := p.parseDesc(desc)
+	return err
+}
+
+/*
+Decode a game description into a mine layout and a start square.
+The start square is optional in a description; if it is absent x
+and y are returned as -1.
+*/
+func (p GameParams) parseDesc(desc string) (grid []int8, x, y int, err error) {
+	wh := p.Width * p.Height
+	x, y = -1, -1
+
+	if isDigitPrefix(desc) {
+		x, desc = atoiPrefix(desc)
+		if x < 0 || x >= p.Width {
+			return nil, 0, 0, fmt.Errorf("initial x-coordinate was out of range")
+		}
+		if desc == "" || desc[0] != ',' {
+			return nil, 0, 0, fmt.Errorf("no ',' after initial x-coordinate in game description")
+		}
+		desc = desc[1:] /* eat comma */
+		if !isDigitPrefix(desc) {
+			return nil, 0, 0, fmt.Errorf("no initial y-coordinate in game description")
+		}
+		y, desc = atoiPrefix(desc)
+		if y < 0 || y >= p.Height {
+			return nil, 0, 0, fmt.Errorf("initial y-coordinate was out of range")
+		}
+		if desc == "" || desc[0] != ',' {
+			return nil, 0, 0, fmt.Errorf("no ',' after initial y-coordinate in game description")
+		}
+		desc = desc[1:] /* eat comma */
+	}
+
+	/* a `c' introduces a count for each square */
+	if desc != "" && desc[0] == 'c' {
+		desc = desc[1:]
+		if len(desc) != wh {
+			return nil, 0, 0, fmt.Errorf("game description is wrong length")
+		}
+		grid = make([]int8, wh)
+		for i := range desc {
+			if desc[i] < '0' || desc[i] > '0'+MaxMinesPerCell {
+				return nil, 0, 0, fmt.Errorf("invalid character in game description")
+			}
+			grid[i] = int8(desc[i] - '0')
+		}
+		return grid, x, y, nil
+	}
+
+	/* eat `m' for `masked' or `u' for `unmasked', if present */
+	masked := false
+	if desc != "" && (desc[0] == 'm' || desc[0] == 'u') {
+		masked = desc[0] == 'm'
+		desc = desc[1:]
+	}
+
+	/* now just check length of remainder */
+	if len(desc) != (wh+3)/4 {
+		return nil, 0, 0, fmt.Errorf("game description is wrong length")
+	}
+
+	bmp := make([]byte, (wh+7)/8)
+	for i := range desc {
+		v, err := strconv.ParseUint(desc[i:i+1], 16, 8)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("invalid character in game description")
+		}
+		if i%2 == 0 {
+			v <<= 4
+		}
+		bmp[i/2] |= byte(v)
+	}
+	if masked {
+		obfuscateBitmap(bmp, wh, true)
+	}
+
+	grid = make([]int8, wh)
+	for i := range grid {
+		if bmp[i/8]&(0x80>>(i%8)) != 0 {
+			grid[i] = 1
+		}
+	}
+	return grid, x, y, nil
+}
+
+/*
+NewGameFromDesc starts a game on the layout given by a game
+description, opening its start square. A start square given in the
+description takes precedence over x and y. The description must
+contain exactly as many mines as the parameters ask for.
+
+Since its layout may be known in advance, such a game is marked as
+having a custom layout.
+*/
+func NewGameFromDesc(params GameParams, desc string, x, y int) (*GameState, error) {
+	if err := params.validate(Limits{}, false); err != nil {
+		return nil, err
+	}
+	grid, dx, dy, err := params.parseDesc(desc)
+	if err != nil {
+		return nil, err
+	}
+	if dx >= 0 {
+		x, y = dx, dy
+	}
+	if !params.PointInBounds(x, y) {
+		return nil, fmt.Errorf("no initial square in game description")
+	}
+	if err := params.checkLayout(grid, x, y); err != nil {
+		return nil, err
+	}
+
+	state, err := newGameFromLayout(params, grid, x, y)
+	if err != nil {
+		return nil, err
+	}
+	state.CustomLayout = true
+	return state, nil
+}
@@ -0,0 +1,73 @@
+package mines
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestObfuscateBitmap(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	for _, bits := range []int{1, 7, 8, 81, 256, 480} {
+		bmp := make([]byte, (bits+7)/8)
+		for i := range bits {
+			if r.IntN(2) == 0 {
+				bmp[i/8] |= 0x80 >> (i % 8)
+			}
+		}
+		orig := slices.Clone(bmp)
+		obfuscateBitmap(bmp, bits, false)
+		obfuscateBitmap(bmp, bits, true)
+		if !slices.Equal(bmp, orig) {
+			t.Errorf("%d bits: expected %x, got %x", bits, orig, bmp)
+		}
+	}
+}
+
+func TestDescriptionRoundTrip(t *testing.T) {
+	params := GameParams{Width: 16, Height: 16, MineCount: 40}
+	game, err := NewGame(params, 3, 5, rand.New(rand.NewPCG(1, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, obfuscate := range []bool{false, true} {
+		desc := describeLayout(game.Grid, game.StartX, game.StartY, obfuscate)
+		if err := params.ValidateDesc(desc); err != nil {
+			t.Fatalf("%q: %v", desc, err)
+		}
+		replay, err := NewGameFromDesc(params, desc, -1, -1)
+		if err != nil {
+			t.Fatalf("%q: %v", desc, err)
+		}
+		if !slices.Equal(replay.Grid, game.Grid) ||
+			!slices.Equal(replay.PlayerGrid, game.PlayerGrid) {
+			t.Errorf("%q does not describe the original game", desc)
+		}
+	}
+}
+
+func TestValidateDesc(t *testing.T) {
+	params := GameParams{Width: 3, Height: 3, MineCount: 1}
+	tests := []struct {
+		desc  string
+		valid bool
+	}{
+		{"0,0,u008", true},
+		{"u008", true},
+		{"008", false}, /* read as an x-coordinate */
+		{"3,0,u008", false},
+		{"0,3,u008", false},
+		{"0u008", false},
+		{"0,u008", false},
+		{"0,0u008", false},
+		{"0,0,u08", false},
+		{"0,0,u00g", false},
+	}
+	for _, test := range tests {
+		err := params.ValidateDesc(test.desc)
+		if (err == nil) != test.valid {
+			t.Errorf("%q: expected valid = %v, got %v", test.desc, test.valid, err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package mines
+
+import "time"
+
+/*
+How well a game was played, by the measures minesweeper players
+usually compare themselves on. The 3BV is always that of the whole
+board, so on a lost game these flatter the player less than they
+would if only the part of the board they cleared were counted.
+*/
+type Efficiency struct {
+	BBBVPerSecond float64
+	IOE           float64 /* 3BV per click */
+	Correctness   float64 /* share of clicks that changed something */
+	FlagAccuracy  float64 /* share of flags that were right */
+}
+
+func (s *GameState) Efficiency(elapsed time.Duration) Efficiency {
+	var e Efficiency
+	bbbv := float64(s.BoardStats().BBBV)
+	if secs := elapsed.Seconds(); secs > 0 {
+		e.BBBVPerSecond = bbbv / secs
+	}
+	if total := s.Clicks.Total(); total > 0 {
+		e.IOE = bbbv / float64(total)
+		e.Correctness = float64(total-s.Clicks.Wasted) / float64(total)
+	}
+
+	/*
+	 * Flags are judged the same way RevealPlayerGrid does, so
+	 * that this gives the same answer before and after it.
+	 */
+	var right, wrong int
+	for i, c := range s.PlayerGrid {
+		switch {
+		case c == CorrectlyFlagged, c.IsFlag() && c.FlagCount() == int(s.Grid[i]):
+			right++
+		case c == FalselyFlagged, c.IsFlag():
+			wrong++
+		}
+	}
+	if right+wrong > 0 {
+		e.FlagAccuracy = float64(right) / float64(right+wrong)
+	}
+	return e
+}
@@ -8,3 +8,23 @@ type AssertionError struct {
 func (e AssertionError) Error() string {
 	return e.message
 }
+
+/*
+ParamsError says which of a game's parameters is out of range and
+what it was expected to keep to.
+*/
+type ParamsError struct {
+	Field      string /* the parameter at fault, as clients name it */
+	Constraint string /* "min", "max", "max_squares", "max_mines", "wrap", "mines_per_cell" or "unique" */
+	Limit      int    /* the bound that was broken, for "min" and "max" kinds */
+	Err        error
+}
+
+// [ParamsError] implements [error]
+func (e ParamsError) Error() string {
+	return e.Err.Error()
+}
+
+func (e ParamsError) Unwrap() error {
+	return e.Err
+}
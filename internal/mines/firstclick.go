@@ -0,0 +1,101 @@
+package mines
+
+import "fmt"
+
+/*
+What the first click of a game is guaranteed, which decides where
+mines may go when the board is generated around it.
+*/
+type FirstClick int8
+
+const (
+	/*
+	 * The clicked square and all its neighbours are clear, so the
+	 * first click always opens an area. This is what mines.c does.
+	 */
+	FirstClickOpening FirstClick = iota
+
+	/* Only the clicked square is clear. */
+	FirstClickSafe
+
+	/*
+	 * Nothing is kept clear, and the first click can lose the
+	 * game.
+	 */
+	FirstClickNone
+
+	/*
+	 * The server picks the start square and keeps it and its
+	 * neighbours clear, but leaves it covered for the player to
+	 * open.
+	 */
+	FirstClickChosen
+)
+
+func (f FirstClick) String() string {
+	switch f {
+	case FirstClickOpening:
+		return "opening"
+	case FirstClickSafe:
+		return "safe"
+	case FirstClickNone:
+		return "none"
+	case FirstClickChosen:
+		return "chosen"
+	default:
+		return fmt.Sprintf("FirstClick(%d)", int8(f))
+	}
+}
+
+func ParseFirstClick(s string) (FirstClick, error) {
+	switch s {
+	case "opening", "":
+		return FirstClickOpening, nil
+	case "safe":
+		return FirstClickSafe, nil
+	case "none":
+		return FirstClickNone, nil
+	case "chosen":
+		return FirstClickChosen, nil
+	default:
+		return FirstClickOpening, fmt.Errorf(`unknown first click policy "%s"`, s)
+	}
+}
+
+func (f FirstClick) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+func (f *FirstClick) UnmarshalText(text []byte) (err error) {
+	*f, err = ParseFirstClick(string(text))
+	return err
+}
+
+/*
+Report whether the policy keeps square (x,y) clear of mines on a
+board started from (sx,sy).
+*/
+func (p GameParams) keepsClear(sx, sy, x, y int) bool {
+	switch p.FirstClick {
+	case FirstClickSafe:
+		return x == sx && y == sy
+	case FirstClickNone:
+		return false
+	default:
+		return p.topology().near(sx, sy, x, y)
+	}
+}
+
+/*
+Return how many squares the policy keeps clear on a new board.
+*/
+func (p GameParams) clearSquares() int {
+	switch p.FirstClick {
+	case FirstClickSafe:
+		return 1
+	case FirstClickNone:
+		return 0
+	default:
+		return 1 + len(p.topology().neighbours(0))
+	}
+}
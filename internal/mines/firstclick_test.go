@@ -0,0 +1,104 @@
+package mines
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestFirstClick(t *testing.T) {
+	r := rand.New(rand.NewPCG(7, 8))
+	numbered, dead := 0, 0
+	for range 50 {
+		params := GameParams{Width: 9, Height: 9, MineCount: 40, FirstClick: FirstClickSafe}
+		game, err := NewGame(params, 4, 4, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if game.Dead || game.Grid[4*9+4] != 0 {
+			t.Fatalf("safe first click hit a mine:\n%s", game.Text(true))
+		}
+		if game.PlayerGrid[4*9+4] > 0 {
+			numbered++
+		}
+
+		params.FirstClick = FirstClickNone
+		game, err = NewGame(params, 4, 4, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if game.Dead {
+			dead++
+		}
+	}
+	if numbered == 0 {
+		t.Error("safe first click always opened an area")
+	}
+	if dead == 0 {
+		t.Error("unprotected first click never hit a mine")
+	}
+}
+
+func TestFirstClickSafeUnique(t *testing.T) {
+	params := GameParams{Width: 9, Height: 9, MineCount: 10, Unique: true, FirstClick: FirstClickSafe}
+	game, err := NewGame(params, 4, 4, rand.New(rand.NewPCG(9, 10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := game.Solve(); err != nil {
+		t.Fatal(err)
+	}
+	if !game.Won {
+		t.Fatalf("unique game was not solved:\n%s", game.Text(true))
+	}
+}
+
+func TestFirstClickSafeMoveStart(t *testing.T) {
+	/*
+	 * . * .
+	 * . . .
+	 *
+	 * Starting from the 1 at 0:0 opens nothing else.
+	 */
+	game, err := newGameFromLayout(
+		GameParams{Width: 3, Height: 2, MineCount: 1, FirstClick: FirstClickSafe},
+		[]int8{0, 1, 0, 0, 0, 0}, 0, 0,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.PlayerGrid[0] != 1 {
+		t.Fatalf("expected the start square to be a 1:\n%s", game.Text(true))
+	}
+	if !game.Clone().MoveStart(0, 0) {
+		t.Error("a board should serve its own start square")
+	}
+	if game.Clone().MoveStart(2, 0) {
+		t.Error("a board served a square outside its opening")
+	}
+}
+
+func TestFirstClickChosen(t *testing.T) {
+	params := GameParams{Width: 9, Height: 9, MineCount: 10, Unique: true, FirstClick: FirstClickChosen}
+	game, err := NewGame(params, -1, -1, rand.New(rand.NewPCG(11, 12)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !params.PointInBounds(game.StartX, game.StartY) {
+		t.Fatalf("start square %d,%d is off the board", game.StartX, game.StartY)
+	}
+	if slices.ContainsFunc(game.PlayerGrid, CellState.IsOpen) || game.Clicks.Left != 0 {
+		t.Fatal("the server's choice of start square was opened for the player")
+	}
+	if !game.MoveStart(0, 0) {
+		t.Error("a fresh game with a chosen start should serve any click")
+	}
+
+	game.Do(Move{Kind: MoveOpen, X: game.StartX, Y: game.StartY})
+	if game.Dead || game.PlayerGrid[game.StartY*9+game.StartX] != 0 {
+		t.Fatalf("chosen start square is not blank:\n%s", game.Text(true))
+	}
+	if game.MoveStart(game.StartX, game.StartY) {
+		t.Error("start square moved after the first move")
+	}
+}
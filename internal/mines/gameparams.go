@@ -3,7 +3,10 @@
 package mines
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 )
 
@@ -12,14 +15,93 @@ type GameParams struct {
 	Height    int
 	MineCount int
 	Unique    bool
+	Tiling    Tiling
+	Wrap      bool /* edges wrap round, making the board a torus */
+
+	MinesPerCell int /* most mines a square can hold; 0 means 1 */
+
+	/*
+	 * Hardest a unique board may be to solve; generation retries
+	 * until it gets one no harder.
+	 */
+	MaxGrade Grade
+
+	FirstClick FirstClick /* what the first click is guaranteed */
+
+	/*
+	 * Mines the player can set off and carry on playing; the game
+	 * ends with the one after that. Zero is the usual game.
+	 */
+	Lives int
+
+	/*
+	 * Seconds the player has to clear the board, or zero for no
+	 * limit. The server keeps the time, so the game itself only
+	 * carries the limit along.
+	 */
+	TimeLimit int
 }
 
+/*
+Numbers on a board with several mines to a square can go up to 8
+times as high, and must stay clear of the states from 64 upwards.
+*/
+const MaxMinesPerCell = 7
+
+const MaxLives = 99
+
+const MaxTimeLimit = 24 * 60 * 60 /* a day, in seconds */
+
+/*
+A seed is the width, height, mine count and uniqueness flag,
+separated by colons, followed by any options that are switched on,
+the grade the board may be no harder than, if there is one, and
+the first click policy, unless it is the usual opening.
+*/
+const (
+	seedHex   = "hex"
+	seedWrap  = "wrap"
+	seedMulti = "multi" /* followed by the number of mines per square */
+	seedLives = "lives" /* followed by the number of lives */
+	seedTime  = "time"  /* followed by the time limit in seconds */
+)
+
+var ErrCannotWrap = errors.New(
+	"board cannot wrap: it must be at least 5x5, with an even height if hex",
+)
+
+var ErrMinesPerCell = fmt.Errorf(
+	"a square must hold between 1 and %d mines", MaxMinesPerCell,
+)
+
 func (p GameParams) Seed() string {
 	u := 0
 	if p.Unique {
 		u = 1
 	}
-	return fmt.Sprintf("%d:%d:%d:%d", p.Width, p.Height, p.MineCount, u)
+	seed := fmt.Sprintf("%d:%d:%d:%d", p.Width, p.Height, p.MineCount, u)
+	if p.Tiling == Hex {
+		seed += ":" + seedHex
+	}
+	if p.Wrap {
+		seed += ":" + seedWrap
+	}
+	if n := p.PerCell(); n > 1 {
+		seed += ":" + seedMulti + strconv.Itoa(n)
+	}
+	if p.Lives > 0 {
+		seed += ":" + seedLives + strconv.Itoa(p.Lives)
+	}
+	if p.TimeLimit > 0 {
+		seed += ":" + seedTime + strconv.Itoa(p.TimeLimit)
+	}
+	if p.MaxGrade != GradeAny {
+		seed += ":" + p.MaxGrade.String()
+	}
+	if p.FirstClick != FirstClickOpening {
+		seed += ":" + p.FirstClick.String()
+	}
+	return seed
 }
 
 func (p GameParams) Unpack() (w int, h int, mc int, u bool) {
@@ -27,13 +109,224 @@ func (p GameParams) Unpack() (w int, h int, mc int, u bool) {
 }
 
 func (p GameParams) PointInBounds(x, y int) bool {
-	return y*p.Width+x < p.Width*p.Height
+	return 0 <= x && x < p.Width && 0 <= y && y < p.Height
+}
+
+/*
+PerCell returns the most mines a square can hold.
+*/
+func (p GameParams) PerCell() int {
+	if p.MinesPerCell < 1 {
+		return 1
+	}
+	return p.MinesPerCell
+}
+
+/*
+Check that the number of mines allowed in a square is in range.
+*/
+func (p GameParams) checkMinesPerCell() error {
+	if p.MinesPerCell < 0 || p.MinesPerCell > MaxMinesPerCell {
+		return ErrMinesPerCell
+	}
+	return nil
+}
+
+/*
+Limits bounds the size of the boards a server will make. A limit
+left at zero falls back to what mines.c allows.
+*/
+type Limits struct {
+	MaxWidth   int
+	MaxHeight  int
+	MaxSquares int /* width times height */
+}
+
+func (l Limits) orDefault() Limits {
+	if l.MaxWidth <= 0 || l.MaxWidth > math.MaxInt16 {
+		l.MaxWidth = math.MaxInt16
+	}
+	if l.MaxHeight <= 0 || l.MaxHeight > math.MaxInt16 {
+		l.MaxHeight = math.MaxInt16
+	}
+	if l.MaxSquares <= 0 || l.MaxSquares > math.MaxInt32 {
+		l.MaxSquares = math.MaxInt32
+	}
+	return l
+}
+
+/*
+Validate checks that a board can be generated with these
+parameters and is no larger than limits allow. It returns a
+[ParamsError] naming the first parameter at fault.
+*/
+func (p GameParams) Validate(limits Limits) error {
+	return p.validate(limits, true)
+}
+
+/*
+ValidateLayout is like [GameParams.Validate] for a board whose
+layout is given rather than generated, which may have mines right
+next to its start square.
+*/
+func (p GameParams) ValidateLayout(limits Limits) error {
+	return p.validate(limits, false)
+}
+
+/*
+Check the parameters as validate_params does. Unless full is set
+the board is one that already exists, so it needn't have room for
+the solver to work, nor a clear area round the start square.
+*/
+func (p GameParams) validate(limits Limits, full bool) error {
+	limits = limits.orDefault()
+
+	if full && p.Unique && (p.Width <= 2 || p.Height <= 2) {
+		field := "width"
+		if p.Width > 2 {
+			field = "height"
+		}
+		return ParamsError{field, "min", 3, errors.New(
+			"width and height must both be greater than two",
+		)}
+	}
+	if p.Width < 1 || p.Height < 1 {
+		field := "width"
+		if p.Width >= 1 {
+			field = "height"
+		}
+		return ParamsError{field, "min", 1, errors.New(
+			"width and height must both be at least one",
+		)}
+	}
+	if p.Width > limits.MaxWidth {
+		return ParamsError{"width", "max", limits.MaxWidth, fmt.Errorf(
+			"width must be at most %d", limits.MaxWidth,
+		)}
+	}
+	if p.Height > limits.MaxHeight {
+		return ParamsError{"height", "max", limits.MaxHeight, fmt.Errorf(
+			"height must be at most %d", limits.MaxHeight,
+		)}
+	}
+	if p.Width > limits.MaxSquares/p.Height {
+		return ParamsError{"width", "max_squares", limits.MaxSquares, fmt.Errorf(
+			"width times height must be at most %d", limits.MaxSquares,
+		)}
+	}
+	if err := p.checkMinesPerCell(); err != nil {
+		return ParamsError{"mines_per_cell", "mines_per_cell", MaxMinesPerCell, err}
+	}
+	if p.Wrap && !p.CanWrap() {
+		return ParamsError{"wrap", "wrap", minWrapSize, ErrCannotWrap}
+	}
+	if p.MaxGrade < GradeAny || p.MaxGrade > GradeHard {
+		return ParamsError{"max_grade", "max", int(GradeHard), fmt.Errorf(
+			"grade must be one of %s, %s or %s", GradeEasy, GradeMedium, GradeHard,
+		)}
+	}
+	if p.MaxGrade != GradeAny && !p.Unique {
+		return ParamsError{"max_grade", "unique", 0, errors.New(
+			"only unique boards can be asked for by grade",
+		)}
+	}
+	if p.Lives < 0 || p.Lives > MaxLives {
+		return ParamsError{"lives", "max", MaxLives, fmt.Errorf(
+			"number of lives must be between 0 and %d", MaxLives,
+		)}
+	}
+	if p.TimeLimit < 0 || p.TimeLimit > MaxTimeLimit {
+		return ParamsError{"time_limit", "max", MaxTimeLimit, fmt.Errorf(
+			"time limit must be between 0 and %d seconds", MaxTimeLimit,
+		)}
+	}
+	if p.FirstClick < FirstClickOpening || p.FirstClick > FirstClickChosen {
+		return ParamsError{"first_click", "max", int(FirstClickChosen), fmt.Errorf(
+			"first click policy must be one of %s, %s, %s or %s",
+			FirstClickOpening, FirstClickSafe, FirstClickNone, FirstClickChosen,
+		)}
+	}
+	if p.FirstClick == FirstClickNone && p.Unique {
+		return ParamsError{"first_click", "unique", 0, errors.New(
+			"a unique board needs a first click that is safe",
+		)}
+	}
+	if p.MineCount < 1 {
+		return ParamsError{"mine_count", "min", 1, errors.New(
+			"number of mines must be greater than zero",
+		)}
+	}
+
+	/*
+	 * A new board keeps clear what the first click policy asks
+	 * for; an existing one only the start square.
+	 */
+	clear := 1
+	if full {
+		clear = p.clearSquares()
+	}
+	if maxMines := (p.Width*p.Height - clear) * p.PerCell(); p.MineCount > maxMines {
+		return ParamsError{"mine_count", "max_mines", max(maxMines, 0), errors.New(
+			"too many mines for grid size",
+		)}
+	}
+	return nil
+}
+
+/*
+CanWrap reports whether the board can be made into a torus.
+*/
+func (p GameParams) CanWrap() bool {
+	return p.Width >= minWrapSize && p.Height >= minWrapSize &&
+		(p.Tiling != Hex || p.Height%2 == 0)
 }
 
 func ParseGameSeed(seed string) (*GameParams, error) {
 	p := &GameParams{}
 	u := 0
-	sseed := strings.ReplaceAll(seed, ":", " ")
+	fields := strings.Split(seed, ":")
+	if len(fields) > 4 {
+		for _, opt := range fields[4:] {
+			switch opt {
+			case seedHex:
+				p.Tiling = Hex
+			case seedWrap:
+				p.Wrap = true
+			case GradeEasy.String(), GradeMedium.String(), GradeHard.String():
+				p.MaxGrade, _ = ParseGrade(opt)
+			case FirstClickSafe.String(), FirstClickNone.String(), FirstClickChosen.String():
+				p.FirstClick, _ = ParseFirstClick(opt)
+			default:
+				if n, ok := strings.CutPrefix(opt, seedLives); ok {
+					lives, err := strconv.Atoi(n)
+					if err != nil || lives < 1 || lives > MaxLives {
+						return nil, fmt.Errorf(`invalid game params seed option "%s"`, opt)
+					}
+					p.Lives = lives
+					continue
+				}
+				if n, ok := strings.CutPrefix(opt, seedTime); ok {
+					limit, err := strconv.Atoi(n)
+					if err != nil || limit < 1 || limit > MaxTimeLimit {
+						return nil, fmt.Errorf(`invalid game params seed option "%s"`, opt)
+					}
+					p.TimeLimit = limit
+					continue
+				}
+				n, ok := strings.CutPrefix(opt, seedMulti)
+				if !ok {
+					return nil, fmt.Errorf(`invalid game params seed option "%s"`, opt)
+				}
+				perCell, err := strconv.Atoi(n)
+				if err != nil || perCell < 2 || perCell > MaxMinesPerCell {
+					return nil, fmt.Errorf(`invalid game params seed option "%s"`, opt)
+				}
+				p.MinesPerCell = perCell
+			}
+		}
+		fields = fields[:4]
+	}
+	sseed := strings.Join(fields, " ")
 	n, err := fmt.Sscanf(
 		sseed, "%d %d %d %d", &p.Width, &p.Height, &p.MineCount, &u,
 	)
@@ -0,0 +1,73 @@
+package mines
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	limits := Limits{MaxWidth: 30, MaxHeight: 24, MaxSquares: 600}
+	tests := []struct {
+		params     GameParams
+		field      string
+		constraint string
+		limit      int
+	}{
+		{GameParams{Width: 9, Height: 9, MineCount: 10, Unique: true}, "", "", 0},
+		{GameParams{Width: 30, Height: 16, MineCount: 99}, "", "", 0},
+		{GameParams{Width: 9, Height: 9, MineCount: 72}, "", "", 0},
+		{GameParams{Width: 9, Height: 9, MineCount: 74, Tiling: Hex}, "", "", 0},
+		{GameParams{Width: 9, Height: 9, MineCount: 216, MinesPerCell: 3}, "", "", 0},
+		{GameParams{Width: 2, Height: 9, MineCount: 1, Unique: true}, "width", "min", 3},
+		{GameParams{Width: 9, Height: 2, MineCount: 1, Unique: true}, "height", "min", 3},
+		{GameParams{Width: 0, Height: 9, MineCount: 1}, "width", "min", 1},
+		{GameParams{Width: 9, Height: -1, MineCount: 1}, "height", "min", 1},
+		{GameParams{Width: 31, Height: 9, MineCount: 10}, "width", "max", 30},
+		{GameParams{Width: 9, Height: 25, MineCount: 10}, "height", "max", 24},
+		{GameParams{Width: 30, Height: 24, MineCount: 10}, "width", "max_squares", 600},
+		{GameParams{Width: 9, Height: 9, MineCount: 0}, "mine_count", "min", 1},
+		{GameParams{Width: 9, Height: 9, MineCount: -5}, "mine_count", "min", 1},
+		{GameParams{Width: 9, Height: 9, MineCount: 73}, "mine_count", "max_mines", 72},
+		{GameParams{Width: 9, Height: 9, MineCount: 75, Tiling: Hex}, "mine_count", "max_mines", 74},
+		{GameParams{Width: 9, Height: 9, MineCount: 217, MinesPerCell: 3}, "mine_count", "max_mines", 216},
+		{GameParams{Width: 2, Height: 2, MineCount: 1}, "mine_count", "max_mines", 0},
+		{GameParams{Width: 9, Height: 9, MineCount: 10, MinesPerCell: 8}, "mines_per_cell", "mines_per_cell", MaxMinesPerCell},
+		{GameParams{Width: 4, Height: 9, MineCount: 3, Wrap: true}, "wrap", "wrap", minWrapSize},
+		{GameParams{Width: 9, Height: 9, MineCount: 80, FirstClick: FirstClickSafe}, "", "", 0},
+		{GameParams{Width: 9, Height: 9, MineCount: 81, FirstClick: FirstClickNone}, "", "", 0},
+		{GameParams{Width: 9, Height: 9, MineCount: 81, FirstClick: FirstClickSafe}, "mine_count", "max_mines", 80},
+		{GameParams{Width: 9, Height: 9, MineCount: 10, Unique: true, FirstClick: FirstClickNone}, "first_click", "unique", 0},
+		{GameParams{Width: 9, Height: 9, MineCount: 10, FirstClick: 7}, "first_click", "max", int(FirstClickChosen)},
+		{GameParams{Width: 9, Height: 9, MineCount: 10, Lives: 3}, "", "", 0},
+		{GameParams{Width: 9, Height: 9, MineCount: 10, Lives: MaxLives + 1}, "lives", "max", MaxLives},
+		{GameParams{Width: 9, Height: 9, MineCount: 10, TimeLimit: 60}, "", "", 0},
+		{GameParams{Width: 9, Height: 9, MineCount: 10, TimeLimit: -1}, "time_limit", "max", MaxTimeLimit},
+	}
+	for _, test := range tests {
+		err := test.params.Validate(limits)
+		if test.field == "" {
+			if err != nil {
+				t.Errorf("%s: %v", test.params.Seed(), err)
+			}
+			continue
+		}
+		var paramsErr ParamsError
+		if !errors.As(err, &paramsErr) {
+			t.Errorf("%s: expected a ParamsError, got %v", test.params.Seed(), err)
+			continue
+		}
+		if paramsErr.Field != test.field || paramsErr.Constraint != test.constraint ||
+			paramsErr.Limit != test.limit {
+			t.Errorf(
+				"%s: expected %s %s %d, got %+v",
+				test.params.Seed(), test.field, test.constraint, test.limit, paramsErr,
+			)
+		}
+	}
+
+	/* With no limits set the board may be as large as mines.c allows. */
+	huge := GameParams{Width: 1000, Height: 1000, MineCount: 1000}
+	if err := huge.Validate(Limits{}); err != nil {
+		t.Errorf("%s: %v", huge.Seed(), err)
+	}
+}
@@ -3,69 +3,178 @@
 package mines
 
 import (
-	"bytes"
-	"encoding/gob"
+	"context"
 	"log/slog"
 	"math/rand/v2"
+	"slices"
+	"time"
 )
 
 var Log *slog.Logger = slog.Default()
 
 type GameState struct {
 	Dead, Won, UsedSolve bool
-	Grid                 []bool /* real mine points */
+	Grid                 []int8 /* real number of mines in each square */
 	PlayerGrid           Grid   /* player knowledge */
 	GameParams
+	StartX, StartY int  /* square the game was started from */
+	CustomLayout   bool /* layout was supplied rather than generated */
+
+	QuestionMarks bool /* flagging cycles through question marks */
+
+	UsedUndo    bool
+	HistoryBase Grid   /* player grid before the first recorded move */
+	History     []Move /* recorded moves, including undone ones */
+	HistoryPos  int    /* number of moves currently applied */
+
+	Clicks ClickStats
+
+	cover coverCount /* not saved; counted again when needed */
 }
 
-func DecodeGameState(buf []byte) (*GameState, error) {
-	var game GameState
-	err := gob.NewDecoder(bytes.NewBuffer(buf)).Decode(&game)
-	if err != nil {
-		return nil, err
-	}
-	return &game, err
+/*
+The number of squares still covered and the number of those with
+mines in, so that OpenCell can tell whether the game is won without
+scanning the whole grid after every click. Only OpenCell uncovers
+squares; anything that puts back an earlier player grid must reset
+this.
+*/
+type coverCount struct {
+	valid          bool
+	covered, mined int
 }
 
-func (g GameState) Bytes() ([]byte, error) {
-	var buf bytes.Buffer
-	err := gob.NewEncoder(&buf).Encode(g)
-	if err != nil {
-		return nil, err
+func (s *GameState) countCovered() {
+	if s.cover.valid {
+		return
+	}
+	s.cover = coverCount{valid: true}
+	for i, c := range s.PlayerGrid {
+		if c < 0 {
+			s.cover.covered++
+			if s.Grid[i] > 0 {
+				s.cover.mined++
+			}
+		}
 	}
-	return buf.Bytes(), nil
 }
 
 func NewGame(params GameParams, x, y int, r *rand.Rand) (state *GameState, err error) {
-	grid, err := params.newSolvableGrid(x, y, r)
+	state, _, err = NewGameContext(context.Background(), params, x, y, 0, r)
+	return state, err
+}
+
+/*
+How long generating a board took.
+*/
+type GenerateStats struct {
+	Attempts int /* layouts tried, counting the one kept */
+	Duration time.Duration
+}
+
+/*
+NewGameContext generates a game like [NewGame], but gives up with
+[ErrGenerationTimeout] once ctx is done or, if maxAttempts is
+positive, after that many attempts. The stats are filled in either
+way.
+
+If the server chooses the start square, x and y are ignored.
+*/
+func NewGameContext(
+	ctx context.Context, params GameParams, x, y, maxAttempts int, r *rand.Rand,
+) (state *GameState, stats GenerateStats, err error) {
+	if err := params.validate(Limits{}, true); err != nil {
+		return nil, stats, err
+	}
+	if params.FirstClick == FirstClickChosen {
+		x, y = r.IntN(params.Width), r.IntN(params.Height)
+	}
+	start := time.Now()
+	grid, attempts, err := params.newSolvableGrid(ctx, x, y, maxAttempts, r)
+	stats = GenerateStats{Attempts: attempts, Duration: time.Since(start)}
 	if err != nil {
-		return nil, err
+		return nil, stats, err
 	}
+	state, err = newGameFromLayout(params, grid, x, y)
+	return state, stats, err
+}
+
+/*
+Set up a game on a layout and make its first click, unless the
+server chose the start square, which is left for the player to
+open. Without any protection the first click may lose the game.
+*/
+func newGameFromLayout(params GameParams, grid []int8, x, y int) (*GameState, error) {
 	playerGrid := make(Grid, len(grid))
 	for i := range playerGrid {
 		playerGrid[i] = Unknown
 	}
-	state = &GameState{
+	state := &GameState{
 		GameParams: params,
 		Grid:       grid,
 		PlayerGrid: playerGrid,
+		StartX:     x,
+		StartY:     y,
+	}
+	if params.FirstClick == FirstClickChosen {
+		return state, nil
 	}
-	if state.OpenCell(x, y) != 0 {
+	state.Clicks.Left = 1 /* the click that started the game */
+	if state.OpenCell(x, y) != 0 && params.FirstClick != FirstClickNone {
 		return nil, AssertionError{"mine in starting cell"}
 	}
-	return state, err
+	return state, nil
+}
+
+/*
+MoveStart makes (x,y) the start square of a game nobody has moved
+in yet, if opening it would have opened exactly what the original
+start square did. That holds for any blank square in the opening
+the game started with, which is every blank square open so far, so
+a board generated for one start square serves all of them. It
+always holds for the start square itself, which needn't be blank
+when the first click is only kept safe.
+
+Where the server chooses the start square, any board serves, and
+the start square stays where it is.
+*/
+func (s *GameState) MoveStart(x, y int) bool {
+	if s.FirstClick == FirstClickChosen {
+		return s.HistoryBase == nil
+	}
+	if !s.PointInBounds(x, y) || s.HistoryBase != nil {
+		return false
+	}
+	if (x != s.StartX || y != s.StartY) && s.PlayerGrid[y*s.Width+x] != 0 {
+		return false
+	}
+	s.StartX, s.StartY = x, y
+	return true
 }
 
 func (s *GameState) OpenCell(x, y int) int {
 	i := y*s.Width + x
-	if s.Grid[i] {
+	if s.PlayerGrid[i] == ExplodedMine {
+		return -1 /* it has already gone off */
+	}
+	s.countCovered()
+	if s.PlayerGrid[i] < 0 {
+		s.cover.covered--
+	}
+	if s.Grid[i] > 0 {
 		/*
 		 * The player has landed on a mine. Bad luck. Expose the
 		 * mine that killed them, but not the rest (in case they
 		 * want to Undo and carry on playing).
+		 *
+		 * With lives to spare, the mine stays exposed and play
+		 * goes on.
 		 */
-		s.Dead = true
+		s.cover.mined--
 		s.PlayerGrid[i] = ExplodedMine
+		if s.Explosions() > s.Lives {
+			s.Dead = true
+		}
 		return -1
 	}
 
@@ -75,52 +184,38 @@ func (s *GameState) OpenCell(x, y int) int {
 	s.PlayerGrid[i] = Todo /* `todo' value internal to this func */
 
 	/*
-	 * Now go through the grid finding all `todo' values and
-	 * opening them. Every time one of them turns out to have no
-	 * neighbouring mines, we add all its unopened neighbours to
-	 * the list as well.
-	 *
-	 * FIXME: We really ought to be able to do this better than
-	 * using repeated N^2 scans of the grid.
+	 * Now work through the to-do squares in the order they were
+	 * found, opening each one. Every
+	 * time one of them turns out to have no neighbouring mines, we
+	 * add all its unopened neighbours to the list as well. A
+	 * square is marked to-do as it goes on the list, so it can't
+	 * be added twice.
 	 */
-	for {
-		doneSomething := false
-		for yy := range s.Height {
-			for xx := range s.Width {
-				if s.PlayerGrid[yy*s.Width+xx] == Todo {
-					v := 0
-					for dx := -1; dx <= 1; dx++ {
-						for dy := -1; dy <= 1; dy++ {
-							xxx := xx + dx
-							yyy := yy + dy
-							if xxx >= 0 && xxx < s.Width &&
-								yyy >= 0 && yyy < s.Height &&
-								s.Grid[yyy*s.Width+xxx] {
-								v++
-							}
-						}
-					}
-					s.PlayerGrid[yy*s.Width+xx] = CellState(v)
-					if v == 0 {
-						for dx := -1; dx <= 1; dx++ {
-							for dy := -1; dy <= 1; dy++ {
-								xxx := xx + dx
-								yyy := yy + dy
-								if xxx >= 0 && xxx < s.Width &&
-									yyy >= 0 && yyy < s.Height &&
-									s.PlayerGrid[yyy*s.Width+xxx] == Unknown {
-									s.PlayerGrid[yyy*s.Width+xxx] = Todo
-								}
-							}
-						}
-					}
-					doneSomething = true
-				}
+	t := s.topology()
+	todo := []int{i}
+	for head := 0; head < len(todo); head++ {
+		j := todo[head]
+		xx, yy := j%s.Width, j/s.Width
+
+		var nbrs [8]int
+		n, v := 0, 0
+		for _, d := range t.neighbours(yy) {
+			xxx, yyy, ok := t.normalize(xx+d.dx, yy+d.dy)
+			if ok {
+				nbrs[n] = yyy*s.Width + xxx
+				v += int(s.Grid[nbrs[n]])
+				n++
 			}
 		}
-
-		if !doneSomething {
-			break
+		s.PlayerGrid[j] = CellState(v)
+		if v == 0 {
+			for _, k := range nbrs[:n] {
+				if s.PlayerGrid[k] == Unknown || s.PlayerGrid[k] == Question {
+					s.PlayerGrid[k] = Todo
+					s.cover.covered--
+					todo = append(todo, k)
+				}
+			}
 		}
 	}
 
@@ -130,28 +225,14 @@ func (s *GameState) OpenCell(x, y int) int {
 	}
 
 	/*
-	 * Finally, scan the grid and see if exactly as many squares
-	 * are still covered as there are mines. If so, set the `won'
+	 * Finally, see if exactly as many squares are still covered
+	 * as there are squares with mines in. If so, set the `won'
 	 * flag and fill in mine markers on all covered squares.
 	 */
-	var nmines, ncovered int
-	for yy := range s.Height {
-		for xx := range s.Width {
-			if s.PlayerGrid[yy*s.Width+xx] < 0 {
-				ncovered++
-			}
-			if s.Grid[yy*s.Width+xx] {
-				nmines++
-			}
-		}
-	}
-
-	if ncovered == nmines {
-		for yy := range s.Height {
-			for xx := range s.Width {
-				if s.PlayerGrid[yy*s.Width+xx] == -2 {
-					s.PlayerGrid[yy*s.Width+xx] = UnflaggedMine
-				}
+	if s.cover.covered == s.cover.mined {
+		for j, c := range s.PlayerGrid {
+			if c == Unknown || c == Question {
+				s.PlayerGrid[j] = UnflaggedMine
 			}
 		}
 		s.Won = true
@@ -160,34 +241,69 @@ func (s *GameState) OpenCell(x, y int) int {
 	return 0
 }
 
+/*
+Flagging toggles a square between unknown and flagged, or, if the
+game has question marks enabled, cycles it through unknown, flagged
+and question-marked like the classic Windows game does.
+
+Where a square can hold several mines, each flag in turn claims
+one more mine, up to the most the square can hold, before the
+cycle moves on.
+*/
 func (s *GameState) FlagCell(x, y int) {
 	i := y*s.Width + x
-	if s.PlayerGrid[i] == Unknown {
+	switch c := s.PlayerGrid[i]; {
+	case c == Unknown:
 		s.PlayerGrid[i] = Flagged
-	} else if s.PlayerGrid[i] == Flagged {
+	case c.IsFlag() && c.FlagCount() < s.PerCell():
+		s.PlayerGrid[i] = FlagOf(c.FlagCount() + 1)
+	case c.IsFlag():
+		if s.QuestionMarks {
+			s.PlayerGrid[i] = Question
+		} else {
+			s.PlayerGrid[i] = Unknown
+		}
+	case c == Question:
+		if s.QuestionMarks {
+			s.PlayerGrid[i] = Unknown
+		} else {
+			s.PlayerGrid[i] = Flagged
+		}
+	}
+}
+
+/*
+Question-marking toggles a covered square between question-marked
+and unknown, replacing any flag on it.
+*/
+func (s *GameState) QuestionCell(x, y int) {
+	i := y*s.Width + x
+	switch c := s.PlayerGrid[i]; {
+	case c == Unknown, c.IsFlag():
+		s.PlayerGrid[i] = Question
+	case c == Question:
 		s.PlayerGrid[i] = Unknown
 	}
 }
 
 func (s *GameState) ChordCell(x, y int) {
 	i := y*s.Width + x
-	if !(0 <= s.PlayerGrid[i] && s.PlayerGrid[i] <= 8) {
+	if !s.PlayerGrid[i].IsOpen() {
 		return
 	}
 	c := int(s.PlayerGrid[i])
-	js := make([]int, 0, 8-c)
+	js := make([]int, 0, 8)
 	m := 0
-	for dx := -1; dx <= +1; dx++ {
-		for dy := -1; dy <= +1; dy++ {
-			if 0 <= x+dx && x+dx < s.Width &&
-				0 <= y+dy && y+dy < s.Height &&
-				(dx != 0 || dy != 0) {
-				j := (y+dy)*s.Width + (x + dx)
-				if s.PlayerGrid[j] == Flagged {
-					m++
-				} else if s.PlayerGrid[j] == Unknown {
-					js = append(js, j)
-				}
+	t := s.topology()
+	for _, d := range t.neighbours(y) {
+		if xx, yy, ok := t.normalize(x+d.dx, y+d.dy); ok {
+			j := yy*s.Width + xx
+			if s.PlayerGrid[j].IsFlag() {
+				m += s.PlayerGrid[j].FlagCount()
+			} else if s.PlayerGrid[j] == ExplodedMine {
+				m += int(s.Grid[j])
+			} else if s.PlayerGrid[j] == Unknown || s.PlayerGrid[j] == Question {
+				js = append(js, j)
 			}
 		}
 	}
@@ -203,31 +319,50 @@ func (s *GameState) ChordCell(x, y int) {
 	}
 }
 
+/*
+Explosions returns the number of mines the player has set off.
+*/
+func (s *GameState) Explosions() int {
+	n := 0
+	for _, c := range s.PlayerGrid {
+		if c == ExplodedMine {
+			n++
+		}
+	}
+	return n
+}
+
+/*
+LivesLeft returns how many more mines the player can set off
+before the game is lost.
+*/
+func (s *GameState) LivesLeft() int {
+	return max(s.Lives-s.Explosions(), 0)
+}
+
 func (s *GameState) RevealPlayerGrid() {
 	if !(s.Dead || s.Won) {
 		s.Dead = true
 	}
+	t := s.topology()
 	for i := range s.Grid {
-		if s.PlayerGrid[i] == Flagged {
-			if s.Grid[i] {
+		if s.PlayerGrid[i].IsFlag() {
+			if s.PlayerGrid[i].FlagCount() == int(s.Grid[i]) {
 				s.PlayerGrid[i] = CorrectlyFlagged
 			} else {
 				s.PlayerGrid[i] = FalselyFlagged
 			}
 		} else if s.PlayerGrid[i] == Unknown || s.PlayerGrid[i] == Question {
-			if s.Grid[i] {
+			if s.Grid[i] > 0 {
 				s.PlayerGrid[i] = UnflaggedMine
 			} else {
 				c := 0
 				x := i % s.Width
 				y := i / s.Width
-				for dx := -1; dx <= +1; dx++ {
-					for dy := -1; dy <= +1; dy++ {
-						j := (y+dy)*s.Width + (x + dx)
-						if 0 <= j && j < len(s.Grid) &&
-							j != i && s.Grid[j] {
-							c++
-						}
+				for _, d := range t.neighbours(y) {
+					xx, yy, ok := t.normalize(x+d.dx, y+d.dy)
+					if ok {
+						c += int(s.Grid[yy*s.Width+xx])
 					}
 				}
 				s.PlayerGrid[i] = CellState(c)
@@ -235,3 +370,16 @@ func (s *GameState) RevealPlayerGrid() {
 		}
 	}
 }
+
+/*
+Clone returns a deep copy of the game, so that one board can be
+handed out to several sessions.
+*/
+func (s *GameState) Clone() *GameState {
+	c := *s
+	c.Grid = slices.Clone(s.Grid)
+	c.PlayerGrid = slices.Clone(s.PlayerGrid)
+	c.HistoryBase = slices.Clone(s.HistoryBase)
+	c.History = slices.Clone(s.History)
+	return &c
+}
@@ -0,0 +1,264 @@
+package mines
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestFlagCycle(t *testing.T) {
+	tests := []struct {
+		name          string
+		questionMarks bool
+		expected      []CellState
+	}{
+		{"toggle", false, []CellState{Flagged, Unknown, Flagged}},
+		{"cycle", true, []CellState{Flagged, Question, Unknown}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game := newTestGame(2, []bool{false, true})
+			game.QuestionMarks = test.questionMarks
+			for _, expected := range test.expected {
+				game.FlagCell(1, 0)
+				if game.PlayerGrid[1] != expected {
+					t.Fatalf("expected %v, got %v", expected, game.PlayerGrid[1])
+				}
+			}
+		})
+	}
+}
+
+func TestQuestionMarks(t *testing.T) {
+	/*
+	 * * 1 .
+	 * . . .
+	 */
+	game := newTestGame(3, []bool{
+		true, false, false,
+		false, false, false,
+	})
+	game.OpenCell(1, 0)
+	game.QuestionCell(0, 1)
+	game.QuestionCell(2, 1)
+	game.FlagCell(0, 0)
+
+	/* Chording opens question-marked squares like unknown ones. */
+	game.ChordCell(1, 0)
+	if game.PlayerGrid[3] != 1 || game.PlayerGrid[5] != 0 {
+		t.Fatalf("chord did not open question marks:\n%s", game.PlayerGrid.ToString(3))
+	}
+	if !game.Won {
+		t.Fatalf("game should be won:\n%s", game.PlayerGrid.ToString(3))
+	}
+
+	game = newTestGame(3, []bool{
+		true, false, false,
+		false, false, false,
+	})
+	game.QuestionCell(0, 0)
+	game.OpenCell(2, 1)
+	game.OpenCell(0, 1)
+	if !game.Won || game.PlayerGrid[0] != UnflaggedMine {
+		t.Fatalf("question-marked mine was not revealed on win:\n%s", game.PlayerGrid.ToString(3))
+	}
+}
+
+/*
+The flood fill OpenCell used to do, straight from mines.c: scan the
+whole grid for to-do squares until there are none left, then scan
+it again to see whether the game is won. Kept to check the queue
+against, and to measure it by.
+*/
+func openCellScan(s *GameState, x, y int) {
+	i := y*s.Width + x
+	if s.Grid[i] > 0 {
+		s.Dead = true
+		s.PlayerGrid[i] = ExplodedMine
+		return
+	}
+
+	s.PlayerGrid[i] = Todo
+	t := s.topology()
+	for {
+		doneSomething := false
+		for yy := range s.Height {
+			for xx := range s.Width {
+				if s.PlayerGrid[yy*s.Width+xx] == Todo {
+					v := 0
+					for _, d := range t.neighbours(yy) {
+						xxx, yyy, ok := t.normalize(xx+d.dx, yy+d.dy)
+						if ok {
+							v += int(s.Grid[yyy*s.Width+xxx])
+						}
+					}
+					s.PlayerGrid[yy*s.Width+xx] = CellState(v)
+					if v == 0 {
+						for _, d := range t.neighbours(yy) {
+							xxx, yyy, ok := t.normalize(xx+d.dx, yy+d.dy)
+							if ok &&
+								(s.PlayerGrid[yyy*s.Width+xxx] == Unknown ||
+									s.PlayerGrid[yyy*s.Width+xxx] == Question) {
+								s.PlayerGrid[yyy*s.Width+xxx] = Todo
+							}
+						}
+					}
+					doneSomething = true
+				}
+			}
+		}
+		if !doneSomething {
+			break
+		}
+	}
+
+	if s.Dead {
+		return
+	}
+
+	var nmines, ncovered int
+	for i := range s.PlayerGrid {
+		if s.PlayerGrid[i] < 0 {
+			ncovered++
+		}
+		if s.Grid[i] > 0 {
+			nmines++
+		}
+	}
+	if ncovered == nmines {
+		for i, c := range s.PlayerGrid {
+			if c == Unknown || c == Question {
+				s.PlayerGrid[i] = UnflaggedMine
+			}
+		}
+		s.Won = true
+	}
+}
+
+func TestMoveStart(t *testing.T) {
+	params := GameParams{Width: 30, Height: 16, MineCount: 99, Unique: true}
+	r := rand.New(rand.NewPCG(1, 2))
+	game, err := NewGame(params, 15, 8, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := 0
+	for y := range params.Height {
+		for x := range params.Width {
+			g := game.Clone()
+			if !g.MoveStart(x, y) {
+				continue
+			}
+			moved++
+			fresh, err := newGameFromLayout(params, game.Grid, x, y)
+			if err != nil {
+				t.Fatalf("%d,%d: %v", x, y, err)
+			}
+			if !slices.Equal(fresh.PlayerGrid, g.PlayerGrid) {
+				t.Fatalf("starting from %d,%d opens different squares", x, y)
+			}
+		}
+	}
+	if moved == 0 {
+		t.Fatal("start square could not be kept")
+	}
+
+	game.Do(Move{Kind: MoveFlag, X: 0, Y: 0})
+	if game.MoveStart(game.StartX, game.StartY) {
+		t.Error("moved the start of a game already under way")
+	}
+}
+
+func TestOpenCellMatchesScan(t *testing.T) {
+	tests := []GameParams{
+		{Width: 9, Height: 9, MineCount: 10},
+		{Width: 30, Height: 16, MineCount: 99},
+		{Width: 40, Height: 40, MineCount: 60},
+		{Width: 10, Height: 8, MineCount: 12, Tiling: Hex, Wrap: true},
+		{Width: 9, Height: 9, MineCount: 20, MinesPerCell: 3},
+	}
+	for _, params := range tests {
+		t.Run(params.Seed(), func(t *testing.T) {
+			r := rand.New(rand.NewPCG(1, 2))
+			for range 20 {
+				sx, sy := r.IntN(params.Width), r.IntN(params.Height)
+				game, err := NewGame(params, sx, sy, r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				game.QuestionMarks = true
+				scan := game.Clone()
+
+				for !game.Dead && !game.Won {
+					x, y := r.IntN(params.Width), r.IntN(params.Height)
+					switch r.IntN(4) {
+					case 0:
+						game.FlagCell(x, y)
+						scan.FlagCell(x, y)
+					default:
+						game.OpenCell(x, y)
+						openCellScan(scan, x, y)
+					}
+					if !slices.Equal(game.PlayerGrid, scan.PlayerGrid) ||
+						game.Dead != scan.Dead || game.Won != scan.Won {
+						t.Fatalf("opening %d:%d: expected\n%sgot\n%s", x, y,
+							scan.PlayerGrid.ToString(params.Width),
+							game.PlayerGrid.ToString(params.Width))
+					}
+				}
+			}
+		})
+	}
+}
+
+/*
+Openings are opened from the bottom right corner, which is the
+worst place for the scan: each pass over the grid only carries
+the opening one row further up.
+*/
+func benchmarkOpenCell(b *testing.B, open func(s *GameState, x, y int)) {
+	boards := append(presets[:len(presets):len(presets)], []struct {
+		name   string
+		params GameParams
+	}{
+		{
+			name:   "100x100(500)",
+			params: GameParams{Width: 100, Height: 100, MineCount: 500},
+		},
+		{
+			name:   "100x100(0)",
+			params: GameParams{Width: 100, Height: 100, MineCount: 0},
+		},
+	}...)
+	for _, board := range boards {
+		b.Run(board.name, func(b *testing.B) {
+			r := rand.New(rand.NewPCG(1, 2))
+			sx, sy := board.params.Width-1, board.params.Height-1
+			grid, _, err := board.params.newSolvableGrid(context.Background(), sx, sy, 0, r)
+			if err != nil {
+				b.Fatal(err)
+			}
+			game := &GameState{
+				GameParams: board.params,
+				Grid:       grid,
+				PlayerGrid: make(Grid, len(grid)),
+			}
+			for b.Loop() {
+				for i := range game.PlayerGrid {
+					game.PlayerGrid[i] = Unknown
+				}
+				game.cover, game.Won = coverCount{}, false
+				open(game, sx, sy)
+			}
+		})
+	}
+}
+
+func BenchmarkOpenCell(b *testing.B) {
+	benchmarkOpenCell(b, func(s *GameState, x, y int) { s.OpenCell(x, y) })
+}
+
+func BenchmarkOpenCellScan(b *testing.B) {
+	benchmarkOpenCell(b, openCellScan)
+}
@@ -1,37 +1,65 @@
 package mines
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"math/rand/v2"
 )
 
-func (p GameParams) newSolvableGrid(startX, startY int, r *rand.Rand) (grid []bool, err error) {
+var ErrGenerationTimeout = errors.New("could not generate a board in time")
+
+/*
+Generate a layout the way mines.c does, giving up once ctx is done
+or, if maxAttempts is positive, after that many attempts. Unique
+boards can take many attempts; others always take one.
+*/
+func (p GameParams) newSolvableGrid(
+	ctx context.Context, startX, startY, maxAttempts int, r *rand.Rand,
+) (grid []int8, attempt int, err error) {
 	width, height, mineCount, _ := p.Unpack()
+	t := p.topology()
+	perCell := p.PerCell()
 
-	attempt := 0
 	success := false // do { success = false; ... } while (!success)
 	for !success {
+		if err := ctx.Err(); err != nil {
+			return nil, attempt, fmt.Errorf("%w: %w", ErrGenerationTimeout, err)
+		}
+		if maxAttempts > 0 && attempt >= maxAttempts {
+			return nil, attempt, fmt.Errorf(
+				"%w: gave up after %d attempts", ErrGenerationTimeout, attempt,
+			)
+		}
 		attempt++
 
-		grid = make([]bool, width*height)
+		grid = make([]int8, width*height)
 
 		/*
-		 * Start by placing n mines, none of which is at x,y or within
-		 * one square of it.
+		 * Start by placing n mines, keeping clear whatever the
+		 * first click policy promises: usually x,y and everything
+		 * next to it.
 		 */
 		{
-			candidates := make([]int, 0, width*height)
+			candidates := make([]int, 0, width*height*perCell)
 
 			/*
-			* Write down the list of possible mine locations.
+			 * Write down the list of possible mine locations, once
+			 * for every mine each square has room for.
 			 */
 			for y := range height {
 				for x := range width {
-					if absDiff(startY, y) > 1 || absDiff(startX, x) > 1 {
-						candidates = append(candidates, y*width+x)
+					if !p.keepsClear(startX, startY, x, y) {
+						for range perCell {
+							candidates = append(candidates, y*width+x)
+						}
 					}
 				}
 			}
+			if mineCount > len(candidates) {
+				return nil, attempt, fmt.Errorf("too many mines for the board")
+			}
 
 			/*
 			 * Now pick n off the list at random.
@@ -39,7 +67,7 @@ func (p GameParams) newSolvableGrid(startX, startY int, r *rand.Rand) (grid []bo
 			k := len(candidates)
 			for range mineCount {
 				i := r.IntN(k)
-				grid[candidates[i]] = true
+				grid[candidates[i]]++
 				k--
 				candidates[i] = candidates[k]
 			}
@@ -54,33 +82,55 @@ func (p GameParams) newSolvableGrid(startX, startY int, r *rand.Rand) (grid []bo
 		 * We bypass this bit if we're not after a unique grid.
 		 */
 		if p.Unique {
-			solveGrid := make(Grid, 0, width*height)
-			ctx := &mineCtx{
-				grid:  grid,
-				width: width, height: height,
-				sx: startX, sy: startY,
+			solveGrid := make(Grid, width*height)
+			mctx := &mineCtx{
+				grid:     grid,
+				topology: t,
+				perCell:  perCell,
+				sx:       startX, sy: startY,
 				allowBigPerturbs: attempt > 100,
+
+				/*
+				 * Tracing costs little next to the solve itself,
+				 * but there's no point unless someone will read
+				 * it when the solver trips an assertion.
+				 */
+				tracing: Log.Enabled(ctx, slog.LevelDebug),
 			}
 			prevRet := NA
 
 			for {
-				for range width * height {
-					solveGrid = append(solveGrid, Unknown)
+				/*
+				 * A dense board can take many solver passes, so
+				 * don't wait for the attempt to end to notice that
+				 * time is up.
+				 */
+				if err := ctx.Err(); err != nil {
+					return nil, attempt, fmt.Errorf("%w: %w", ErrGenerationTimeout, err)
 				}
 
-				solveGrid[startY*width+startX] = ctx.Open(startX, startY)
-				solveGrid[startY*width+startX] = ctx.Open(startX, startY)
+				for i := range solveGrid {
+					solveGrid[i] = Unknown
+				}
+				mctx.steps, mctx.proved = nil, nil
+
+				solveGrid[startY*width+startX] = mctx.Open(startX, startY)
+				solveGrid[startY*width+startX] = mctx.Open(startX, startY)
 
-				if solveGrid[startY*width+startX] != 0 {
-					Log.Error("assertion failed: mine in first square", "solveGrid", solveGrid, "ctx", ctx)
+				if solveGrid[startY*width+startX] < 0 {
+					Log.Error("assertion failed: mine in first square", "solveGrid", solveGrid, "ctx", mctx)
 					grid = nil
 					err = AssertionError{"mine in first square"}
 					return
 				}
 
-				solveRet, err := mineSolve(width, height, mineCount, solveGrid, ctx, r)
+				solveRet, err := mineSolve(width, height, mineCount, solveGrid, mctx, r)
 				if err != nil {
-					return nil, err
+					if mctx.tracing {
+						Log.Debug("solver failed", "error", err, "attempt", attempt,
+							"trace", mctx.steps, "unproved", mctx.proved)
+					}
+					return nil, attempt, err
 				}
 				if solveRet < 0 || prevRet >= 0 && solveRet >= prevRet {
 					success = false
@@ -93,6 +143,18 @@ func (p GameParams) newSolvableGrid(startX, startY int, r *rand.Rand) (grid []bo
 		} else {
 			success = true
 		}
+
+		/*
+		 * If the board is too hard for the grade asked for, start
+		 * again with another.
+		 */
+		if success && p.MaxGrade != GradeAny {
+			techniques, err := p.techniques(grid, startX, startY)
+			if err != nil {
+				return nil, attempt, err
+			}
+			success = techniques.Grade() <= p.MaxGrade
+		}
 	}
 
 	if !success {
@@ -102,3 +164,86 @@ func (p GameParams) newSolvableGrid(startX, startY int, r *rand.Rand) (grid []bo
 
 	return
 }
+
+/*
+How much work a layout takes to clear, as used by the usual
+minesweeper difficulty measures.
+*/
+type BoardStats struct {
+	BBBV     int /* Bechtel's Board Benchmark Value: fewest clicks to clear the board */
+	Openings int /* connected areas of squares with no mines around them */
+	Islands  int /* connected groups of numbered squares no opening reaches */
+}
+
+func (s *GameState) BoardStats() BoardStats {
+	return s.GameParams.analyseGrid(s.Grid)
+}
+
+/*
+Every opening takes one click, and clears itself and the numbers
+around its edge. Every other safe square is numbered and takes a
+click of its own; the islands are those squares grouped together
+by adjacency.
+*/
+func (p GameParams) analyseGrid(grid []int8) BoardStats {
+	t := p.topology()
+	var stats BoardStats
+
+	numbers := make([]int, len(grid))
+	for i := range grid {
+		x, y := i%p.Width, i/p.Width
+		for _, d := range t.neighbours(y) {
+			if xx, yy, ok := t.normalize(x+d.dx, y+d.dy); ok {
+				numbers[i] += int(grid[yy*p.Width+xx])
+			}
+		}
+	}
+
+	/*
+	 * Flood outwards from each safe square, through squares
+	 * matching the kind of region we're counting, marking every
+	 * square reached. Squares next to an opening are reached too,
+	 * but the flood only carries on from the opening itself.
+	 */
+	reached := make([]bool, len(grid))
+	flood := func(i int, inside func(j int) bool) {
+		reached[i] = true
+		todo := []int{i}
+		for head := 0; head < len(todo); head++ {
+			j := todo[head]
+			if !inside(j) {
+				continue
+			}
+			x, y := j%p.Width, j/p.Width
+			for _, d := range t.neighbours(y) {
+				xx, yy, ok := t.normalize(x+d.dx, y+d.dy)
+				k := yy*p.Width + xx
+				if ok && !reached[k] && grid[k] == 0 {
+					reached[k] = true
+					todo = append(todo, k)
+				}
+			}
+		}
+	}
+
+	for i := range grid {
+		if !reached[i] && grid[i] == 0 && numbers[i] == 0 {
+			stats.Openings++
+			flood(i, func(j int) bool { return numbers[j] == 0 })
+		}
+	}
+	for i := range grid {
+		if grid[i] == 0 && !reached[i] {
+			stats.BBBV++
+		}
+	}
+	for i := range grid {
+		if !reached[i] && grid[i] == 0 {
+			stats.Islands++
+			flood(i, func(j int) bool { return true })
+		}
+	}
+	stats.BBBV += stats.Openings
+
+	return stats
+}
@@ -1,8 +1,11 @@
 package mines
 
 import (
+	"context"
+	"errors"
 	"math/rand/v2"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/vancomm/minesweeper-server/internal/tree234"
@@ -15,6 +18,39 @@ func TestMain(m *testing.M) {
 	m.Run()
 }
 
+/*
+Board sizes the generator is tested, and its callers benchmarked, on.
+*/
+var presets = []struct {
+	name   string
+	params GameParams
+}{
+	{
+		name:   "9x9(10)",
+		params: GameParams{Width: 9, Height: 9, MineCount: 10, Unique: true},
+	},
+	{
+		name:   "9x9(35)",
+		params: GameParams{Width: 9, Height: 9, MineCount: 35, Unique: true},
+	},
+	{
+		name:   "16x16(40)",
+		params: GameParams{Width: 16, Height: 16, MineCount: 40, Unique: true},
+	},
+	{
+		name:   "16x16(99)",
+		params: GameParams{Width: 16, Height: 16, MineCount: 99, Unique: true},
+	},
+	{
+		name:   "30x16(99)",
+		params: GameParams{Width: 30, Height: 16, MineCount: 99, Unique: true},
+	},
+	{
+		name:   "30x16(170)",
+		params: GameParams{Width: 30, Height: 16, MineCount: 170, Unique: true},
+	},
+}
+
 func TestSolvableGridGeneration(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
@@ -22,43 +58,13 @@ func TestSolvableGridGeneration(t *testing.T) {
 
 	t.Parallel()
 
-	tests := []struct {
-		name   string
-		params GameParams
-	}{
-		{
-			name:   "9x9(10)",
-			params: GameParams{Width: 9, Height: 9, MineCount: 10, Unique: true},
-		},
-		{
-			name:   "9x9(35)",
-			params: GameParams{Width: 9, Height: 9, MineCount: 35, Unique: true},
-		},
-		{
-			name:   "16x16(40)",
-			params: GameParams{Width: 16, Height: 16, MineCount: 40, Unique: true},
-		},
-		{
-			name:   "16x16(99)",
-			params: GameParams{Width: 16, Height: 16, MineCount: 99, Unique: true},
-		},
-		{
-			name:   "30x16(99)",
-			params: GameParams{Width: 30, Height: 16, MineCount: 99, Unique: true},
-		},
-		{
-			name:   "30x16(170)",
-			params: GameParams{Width: 30, Height: 16, MineCount: 170, Unique: true},
-		},
-	}
-
-	for _, test := range tests {
+	for _, test := range presets {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 			r := rand.New(rand.NewPCG(1, 2))
 			for sx := range test.params.Width {
 				for sy := range test.params.Height {
-					_, err := test.params.newSolvableGrid(sx, sy, r)
+					_, _, err := test.params.newSolvableGrid(context.Background(), sx, sy, 0, r)
 					if err != nil {
 						t.Log(err)
 						t.Errorf("could not generate game %s @ %d:%d", test.name, sx, sy)
@@ -68,3 +74,109 @@ func TestSolvableGridGeneration(t *testing.T) {
 		})
 	}
 }
+
+/*
+Every hex or torus grid the generator hands out must be solvable
+from its start square without perturbing anything.
+*/
+func TestSolvableTopologyGeneration(t *testing.T) {
+	tests := []GameParams{
+		{Width: 5, Height: 5, MineCount: 5, Unique: true, Wrap: true},
+		{Width: 9, Height: 6, MineCount: 12, Unique: true, Wrap: true},
+		{Width: 16, Height: 16, MineCount: 40, Unique: true, Wrap: true},
+		{Width: 9, Height: 9, MineCount: 10, Unique: true, Tiling: Hex},
+		{Width: 16, Height: 16, MineCount: 40, Unique: true, Tiling: Hex},
+		{Width: 10, Height: 8, MineCount: 12, Unique: true, Tiling: Hex, Wrap: true},
+		{Width: 9, Height: 9, MineCount: 20, Unique: true, MinesPerCell: 3},
+		{Width: 8, Height: 8, MineCount: 16, Unique: true, Wrap: true, MinesPerCell: 2},
+	}
+
+	for _, params := range tests {
+		t.Run(params.Seed(), func(t *testing.T) {
+			r := rand.New(rand.NewPCG(1, 2))
+			for range 20 {
+				sx, sy := r.IntN(params.Width), r.IntN(params.Height)
+				game, err := NewGame(params, sx, sy, r)
+				if err != nil {
+					t.Fatalf("could not generate game @ %d:%d: %v", sx, sy, err)
+				}
+				if err := game.Solve(); err != nil {
+					t.Fatal(err)
+				}
+				if !game.Won {
+					t.Fatalf("game @ %d:%d is not solvable:\n%s",
+						sx, sy, game.PlayerGrid.ToString(params.Width))
+				}
+			}
+		})
+	}
+}
+
+func TestBoardStats(t *testing.T) {
+	tests := []struct {
+		name   string
+		width  int
+		wrap   bool
+		layout string
+		want   BoardStats
+	}{
+		{"no mines", 3, false, "... ... ...", BoardStats{BBBV: 1, Openings: 1}},
+		{"two openings", 4, false, "*... .... ...*", BoardStats{BBBV: 2, Openings: 2}},
+		{"lone number", 3, false, "*.*", BoardStats{BBBV: 1, Islands: 1}},
+		{"two islands", 5, false, "*.*.*", BoardStats{BBBV: 2, Islands: 2}},
+		{"one island", 4, false, "*..*", BoardStats{BBBV: 2, Islands: 1}},
+		{"island by an opening", 5, false, "..... ..... ***.. *.*..",
+			BoardStats{BBBV: 2, Openings: 1, Islands: 1}},
+		{"torus", 5, true, "*.... ..... ..... ..... .....", BoardStats{BBBV: 1, Openings: 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var layout []bool
+			for _, c := range test.layout {
+				if c != ' ' {
+					layout = append(layout, c == '*')
+				}
+			}
+			game := newTestGame(test.width, layout)
+			game.Wrap = test.wrap
+			if got := game.BoardStats(); got != test.want {
+				t.Errorf("expected %+v, got %+v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestGenerationBudget(t *testing.T) {
+	params := GameParams{Width: 30, Height: 16, MineCount: 170, Unique: true}
+	r := rand.New(rand.NewPCG(1, 2))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, stats, err := NewGameContext(ctx, params, 0, 0, 0, r)
+	if !errors.Is(err, ErrGenerationTimeout) || !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled: expected ErrGenerationTimeout, got %v", err)
+	}
+	if stats.Attempts != 0 {
+		t.Errorf("cancelled: expected no attempts, got %d", stats.Attempts)
+	}
+
+	for range 10 {
+		_, stats, err = NewGameContext(context.Background(), params, 0, 0, 2, r)
+		if err != nil && !errors.Is(err, ErrGenerationTimeout) {
+			t.Fatal(err)
+		}
+		if stats.Attempts < 1 || stats.Attempts > 2 {
+			t.Errorf("expected at most 2 attempts, got %d", stats.Attempts)
+		}
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, stats, err = NewGameContext(ctx, params, 0, 0, 0, r)
+	if err != nil && !errors.Is(err, ErrGenerationTimeout) {
+		t.Fatal(err)
+	}
+	if stats.Duration > time.Second {
+		t.Errorf("deadline of 10ms overrun: took %v", stats.Duration)
+	}
+}
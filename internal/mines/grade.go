@@ -0,0 +1,155 @@
+package mines
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+The kinds of reasoning the solver uses, from the simplest up. Each
+of them needs all the ones before it.
+*/
+type Technique uint8
+
+const (
+	/* All the squares round a number are mines, or all are safe. */
+	TechniqueSingle Technique = 1 << iota
+
+	/*
+	 * Comparing the squares round two numbers that overlap: the
+	 * wings either side of the overlap, or one set inside another.
+	 */
+	TechniqueOverlap
+
+	/* Counting how many mines are left on the whole board. */
+	TechniqueCount
+
+	/* Nothing works, and the player has to guess. */
+	TechniqueGuess
+)
+
+var techniqueNames = []string{"single", "overlap", "count", "guess"}
+
+func (t Technique) String() string {
+	var names []string
+	for i, name := range techniqueNames {
+		if t&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "+")
+}
+
+/*
+How hard a board is to clear, judged by the hardest technique it
+needs. As a target in [GameParams], GradeAny asks for no limit.
+*/
+type Grade int8
+
+const (
+	GradeAny Grade = iota
+	GradeEasy
+	GradeMedium
+	GradeHard
+	GradeGuess
+)
+
+func (t Technique) Grade() Grade {
+	switch {
+	case t&TechniqueGuess != 0:
+		return GradeGuess
+	case t&TechniqueCount != 0:
+		return GradeHard
+	case t&TechniqueOverlap != 0:
+		return GradeMedium
+	default:
+		return GradeEasy
+	}
+}
+
+func (g Grade) String() string {
+	switch g {
+	case GradeAny:
+		return "any"
+	case GradeEasy:
+		return "easy"
+	case GradeMedium:
+		return "medium"
+	case GradeHard:
+		return "hard"
+	case GradeGuess:
+		return "guess"
+	default:
+		return fmt.Sprintf("Grade(%d)", int8(g))
+	}
+}
+
+func ParseGrade(s string) (Grade, error) {
+	switch s {
+	case "any", "":
+		return GradeAny, nil
+	case "easy":
+		return GradeEasy, nil
+	case "medium":
+		return GradeMedium, nil
+	case "hard":
+		return GradeHard, nil
+	case "guess":
+		return GradeGuess, nil
+	default:
+		return GradeAny, fmt.Errorf(`unknown grade "%s"`, s)
+	}
+}
+
+func (g Grade) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
+
+func (g *Grade) UnmarshalText(text []byte) (err error) {
+	*g, err = ParseGrade(string(text))
+	return err
+}
+
+/*
+Return the techniques it takes to solve a layout from its start
+square, without moving any mines. The solver is given one more
+technique at a time until it gets all the way, so a board is only
+said to need the ones it can't do without.
+*/
+func (p GameParams) techniques(grid []int8, sx, sy int) (Technique, error) {
+	allow := TechniqueSingle
+	for _, next := range []Technique{TechniqueOverlap, TechniqueCount, TechniqueGuess} {
+		ctx := &mineCtx{
+			grid:      grid,
+			topology:  p.topology(),
+			perCell:   p.PerCell(),
+			sx:        sx,
+			sy:        sy,
+			noPerturb: true,
+			allow:     allow,
+		}
+		solveGrid := make(Grid, p.Width*p.Height)
+		for i := range solveGrid {
+			solveGrid[i] = Unknown
+		}
+		solveGrid[sy*p.Width+sx] = ctx.Open(sx, sy)
+
+		ret, err := mineSolve(p.Width, p.Height, p.MineCount, solveGrid, ctx, nil)
+		if err != nil {
+			return 0, err
+		}
+		if ret == Success {
+			break
+		}
+		allow |= next
+	}
+	return allow, nil
+}
+
+/*
+Techniques returns what it takes to solve the game's board from its
+start square.
+*/
+func (s *GameState) Techniques() (Technique, error) {
+	return s.GameParams.techniques(s.Grid, s.StartX, s.StartY)
+}
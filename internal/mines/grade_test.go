@@ -0,0 +1,97 @@
+package mines
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestGradeTarget(t *testing.T) {
+	tests := []GameParams{
+		{Width: 9, Height: 9, MineCount: 10, Unique: true, MaxGrade: GradeEasy},
+		{Width: 16, Height: 16, MineCount: 40, Unique: true, MaxGrade: GradeMedium},
+		{Width: 9, Height: 9, MineCount: 10, Unique: true, Tiling: Hex, MaxGrade: GradeEasy},
+	}
+	for _, params := range tests {
+		t.Run(params.Seed(), func(t *testing.T) {
+			r := rand.New(rand.NewPCG(1, 2))
+			for range 10 {
+				sx, sy := r.IntN(params.Width), r.IntN(params.Height)
+				game, err := NewGame(params, sx, sy, r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				techniques, err := game.Techniques()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if techniques.Grade() > params.MaxGrade {
+					t.Fatalf("@ %d:%d: board needs %s", sx, sy, techniques)
+				}
+			}
+		})
+	}
+}
+
+/*
+Unique boards never need a guess, and all three levels of reasoning
+turn up among them. Boards nobody checked usually need a guess.
+*/
+func TestGradeSpread(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	seen := make(map[Grade]int)
+	params := GameParams{Width: 16, Height: 16, MineCount: 50, Unique: true}
+	for range 40 {
+		game, err := NewGame(params, 8, 8, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		techniques, err := game.Techniques()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if techniques&TechniqueSingle == 0 {
+			t.Fatalf("board solved without a single simple deduction: %s", techniques)
+		}
+		seen[techniques.Grade()]++
+	}
+	if seen[GradeGuess] > 0 {
+		t.Errorf("%d unique boards need a guess", seen[GradeGuess])
+	}
+	for _, grade := range []Grade{GradeEasy, GradeMedium, GradeHard} {
+		if seen[grade] == 0 {
+			t.Errorf("no %s boards among %v", grade, seen)
+		}
+	}
+
+	params.Unique = false
+	guesses := 0
+	for range 20 {
+		grid, _, err := params.newSolvableGrid(context.Background(), 8, 8, 0, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		techniques, err := params.techniques(grid, 8, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if techniques.Grade() == GradeGuess {
+			guesses++
+		}
+	}
+	if guesses == 0 {
+		t.Error("no random board needed a guess")
+	}
+}
+
+func TestGradeValidate(t *testing.T) {
+	params := GameParams{Width: 9, Height: 9, MineCount: 10, MaxGrade: GradeEasy}
+	if err := params.Validate(Limits{}); err == nil {
+		t.Error("graded board that isn't unique was accepted")
+	}
+	params.Unique = true
+	params.MaxGrade = GradeGuess
+	if err := params.Validate(Limits{}); err == nil {
+		t.Error("board graded to need guessing was accepted")
+	}
+}
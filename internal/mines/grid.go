@@ -13,6 +13,7 @@ const (
 	Question         CellState = -3
 	Unknown          CellState = -2
 	Flagged          CellState = -1
+	multiFlagged     CellState = -20
 	CorrectlyFlagged CellState = 64
 	ExplodedMine     CellState = 65
 	FalselyFlagged   CellState = 66
@@ -21,14 +22,17 @@ const (
 	 * Each item in the `grid' array is one of the following values:
 	 *
 	 * 	- 0 to 8 mean the square is open and has a surrounding mine
-	 * 	  count.
+	 * 	  count. On boards where a square can hold several mines
+	 * 	  the count can go up to 8 times that many.
 	 *
 	 *  - -1 means the square is marked as a mine.
 	 *
+	 * 	- -22 to -27 mean the square is marked as holding 2 to 7
+	 * 	  mines.
+	 *
 	 *  - -2 means the square is unknown.
 	 *
-	 * 	- -3 means the square is marked with a question mark
-	 * 	  (FIXME: do we even want to bother with this?).
+	 * 	- -3 means the square is marked with a question mark.
 	 *
 	 * 	- 64 means the square has had a mine revealed when the game
 	 * 	  was lost.
@@ -49,13 +53,53 @@ func (s CellState) String() string {
 		return " "
 	case s == Flagged:
 		return "*"
-	case 0 <= s && s <= 8:
+	case s.IsFlag():
+		return strconv.Itoa(s.FlagCount()) + "*"
+	case s.IsOpen():
 		return strconv.Itoa(int(s))
 	default:
 		return "!"
 	}
 }
 
+/*
+FlagOf returns the flag marking a square as holding n mines.
+*/
+func FlagOf(n int) CellState {
+	if n <= 1 {
+		return Flagged
+	}
+	return multiFlagged - CellState(n)
+}
+
+func (s CellState) IsFlag() bool {
+	return s == Flagged ||
+		multiFlagged-MaxMinesPerCell <= s && s <= multiFlagged-2
+}
+
+/*
+FlagCount returns the number of mines a flag says its square
+holds, or zero if the square isn't flagged.
+*/
+func (s CellState) FlagCount() int {
+	switch {
+	case s == Flagged:
+		return 1
+	case s.IsFlag():
+		return int(multiFlagged - s)
+	default:
+		return 0
+	}
+}
+
+/*
+IsOpen reports whether the square is open and shows its count of
+surrounding mines.
+*/
+func (s CellState) IsOpen() bool {
+	return 0 <= s && s < CorrectlyFlagged
+}
+
 type Grid []CellState
 
 func (g Grid) ToString(width int) string {
@@ -82,7 +126,8 @@ func (grid *Grid) knownCells(
 	for yy := range 3 {
 		for xx := range 3 {
 			if mask&bit != 0 {
-				i := (y+yy)*w + (x + xx)
+				cx, cy, _ := ctx.normalize(x+xx, y+yy)
+				i := cy*w + cx
 
 				/*
 				 * It's possible that this square is _already_
@@ -93,13 +138,19 @@ func (grid *Grid) knownCells(
 					if mine {
 						(*grid)[i] = Flagged /* and don't open it! */
 					} else {
-						(*grid)[i] = ctx.Open(x+xx, y+yy)
+						(*grid)[i] = ctx.Open(cx, cy)
 
 						if (*grid)[i] == Flagged {
 							return AssertionError{"grid[i] != -1"}
 						}
 					}
 					std.add(i)
+					if ctx.onKnown != nil {
+						ctx.onKnown(i, mine)
+					}
+					if ctx.tracing {
+						ctx.proved = append(ctx.proved, Hint{X: cx, Y: cy, Mine: mine})
+					}
 				}
 			}
 			bit <<= 1
@@ -0,0 +1,60 @@
+package mines
+
+import (
+	"errors"
+	"slices"
+)
+
+type Hint struct {
+	X, Y int
+	Mine bool
+}
+
+/*
+Return the player's knowledge of the grid in the form the solver
+expects. Flags and question marks are only the player's opinion,
+so they are treated as unknown squares, but a mine that has gone
+off is known for certain.
+*/
+func (s *GameState) knowledge() Grid {
+	grid := slices.Clone(s.PlayerGrid)
+	for i, c := range grid {
+		if c == ExplodedMine {
+			grid[i] = Flagged
+		} else if !c.IsOpen() {
+			grid[i] = Unknown
+		}
+	}
+	return grid
+}
+
+var ErrGameOver = errors.New("game is over")
+
+/*
+Hint runs the solver on the player's current position and returns
+the first square it can prove to be safe or a mine that the
+player has not already opened or flagged. A nil hint means no
+such deduction exists and the player has to guess. A game that is
+over has nothing to hint at, and gives [ErrGameOver].
+
+Asking for a hint marks the game as solver-assisted.
+*/
+func (s *GameState) Hint() (*Hint, error) {
+	if s.Dead || s.Won {
+		return nil, ErrGameOver
+	}
+	s.UsedSolve = true
+
+	deductions, err := s.deduce()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deductions {
+		i := d.Y*s.Width + d.X
+		if d.Mine && s.PlayerGrid[i].FlagCount() == int(s.Grid[i]) {
+			continue
+		}
+		return &d, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,79 @@
+package mines
+
+import (
+	"errors"
+	"testing"
+)
+
+func newTestGame(width int, layout []bool) *GameState {
+	mineCount := 0
+	grid := make([]int8, len(layout))
+	for i, m := range layout {
+		if m {
+			grid[i] = 1
+			mineCount++
+		}
+	}
+	playerGrid := make(Grid, len(layout))
+	for i := range playerGrid {
+		playerGrid[i] = Unknown
+	}
+	return &GameState{
+		GameParams: GameParams{
+			Width: width, Height: len(grid) / width, MineCount: mineCount,
+		},
+		Grid:       grid,
+		PlayerGrid: playerGrid,
+	}
+}
+
+func TestHint(t *testing.T) {
+	game := newTestGame(4, []bool{false, false, true, false})
+	game.OpenCell(0, 0)
+
+	hint, err := game.Hint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hint == nil || *hint != (Hint{X: 2, Y: 0, Mine: true}) {
+		t.Fatalf("expected mine at 2:0, got %+v", hint)
+	}
+	if !game.UsedSolve {
+		t.Error("hint was not recorded on the game")
+	}
+
+	game.FlagCell(2, 0)
+	hint, err = game.Hint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hint == nil || *hint != (Hint{X: 3, Y: 0, Mine: false}) {
+		t.Fatalf("expected safe square at 3:0, got %+v", hint)
+	}
+}
+
+func TestHintNeedsGuess(t *testing.T) {
+	game := newTestGame(4, []bool{true, false, false, true})
+	game.OpenCell(1, 0)
+	game.FlagCell(3, 0)
+
+	hint, err := game.Hint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hint != nil {
+		t.Fatalf("expected no deduction, got %+v", hint)
+	}
+}
+
+func TestHintGameOver(t *testing.T) {
+	game := newTestGame(4, []bool{false, false, true, false})
+	game.OpenCell(2, 0)
+
+	if _, err := game.Hint(); !errors.Is(err, ErrGameOver) {
+		t.Fatalf("expected %v, got %v", ErrGameOver, err)
+	}
+	if game.UsedSolve {
+		t.Error("a hint on a finished game was recorded")
+	}
+}
@@ -0,0 +1,212 @@
+package mines
+
+import "slices"
+
+type MoveKind uint8
+
+const (
+	MoveOpen MoveKind = iota + 1
+	MoveFlag
+	MoveChord
+	MoveSolve
+	MoveQuestion
+)
+
+type Move struct {
+	Kind MoveKind
+	X, Y int
+}
+
+func (s *GameState) apply(m Move) error {
+	switch m.Kind {
+	case MoveOpen:
+		s.OpenCell(m.X, m.Y)
+	case MoveFlag:
+		s.FlagCell(m.X, m.Y)
+	case MoveChord:
+		s.ChordCell(m.X, m.Y)
+	case MoveSolve:
+		return s.solve()
+	case MoveQuestion:
+		s.QuestionCell(m.X, m.Y)
+	}
+	return nil
+}
+
+/*
+The clicks a player has made over a game. Right clicks are those
+that place or clear a flag or question mark. Solving the game and
+moving through its history aren't clicks.
+*/
+type ClickStats struct {
+	Left, Right, Chord int
+	Wasted             int /* clicks that changed nothing */
+}
+
+func (c ClickStats) Total() int {
+	return c.Left + c.Right + c.Chord
+}
+
+func (c *ClickStats) count(kind MoveKind, changed bool) {
+	switch kind {
+	case MoveOpen:
+		c.Left++
+	case MoveFlag, MoveQuestion:
+		c.Right++
+	case MoveChord:
+		c.Chord++
+	default:
+		return
+	}
+	if !changed {
+		c.Wasted++
+	}
+}
+
+/*
+Do makes a move and records it in the history, discarding any
+moves that were undone before it. Moves that do not change the
+player grid are not recorded, and moves on a finished game are
+ignored. Every click the player makes is counted, whether it
+changes anything or not.
+*/
+func (s *GameState) Do(m Move) error {
+	if s.Dead || s.Won {
+		return nil
+	}
+
+	/*
+	 * The history is replayed on top of the position the first
+	 * recorded move was made from, so keep a copy of it.
+	 */
+	if s.HistoryBase == nil {
+		s.HistoryBase = slices.Clone(s.PlayerGrid)
+	}
+
+	before := slices.Clone(s.PlayerGrid)
+	if err := s.apply(m); err != nil {
+		return err
+	}
+	changed := !slices.Equal(before, s.PlayerGrid)
+	s.Clicks.count(m.Kind, changed)
+	if !changed {
+		return nil
+	}
+
+	s.History = append(s.History[:s.HistoryPos], m)
+	s.HistoryPos++
+	return nil
+}
+
+/*
+Only a loss caused by the player's own click can be undone, and
+only while the rest of its mines are hidden: a forfeited game, or
+one whose time ran out, has had them revealed, and a won game has
+nothing left to take back.
+*/
+func (s *GameState) CanUndo() bool {
+	if s.HistoryPos == 0 || s.Won {
+		return false
+	}
+	return !s.Dead || !s.revealed()
+}
+
+func (s *GameState) CanRedo() bool {
+	return s.HistoryPos < len(s.History) && !s.Dead && !s.Won
+}
+
+/*
+Finished reports whether the game is over for good: won, or lost
+in a way that can no longer be undone. Until then the layout is
+not to be shown.
+*/
+func (s *GameState) Finished() bool {
+	return s.Won || s.Dead && !s.CanUndo()
+}
+
+/*
+Whether RevealPlayerGrid has been over the board. It leaves no
+square covered, which losing by a click never does: the game was
+not won, so some square besides the mine that went off was still
+covered, and a chord leaves its own flags in place.
+*/
+func (s *GameState) revealed() bool {
+	for _, c := range s.PlayerGrid {
+		if c < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+Undo takes back the last recorded move by replaying the history
+up to it. Any game that has been undone is marked as such.
+
+Every recorded move has already been applied successfully once,
+and moves are deterministic, so replaying them cannot fail.
+*/
+func (s *GameState) Undo() bool {
+	if !s.CanUndo() {
+		return false
+	}
+	s.replay(s.HistoryPos - 1)
+	s.UsedUndo = true
+	return true
+}
+
+func (s *GameState) Redo() bool {
+	if !s.CanRedo() {
+		return false
+	}
+	s.apply(s.History[s.HistoryPos])
+	s.HistoryPos++
+	return true
+}
+
+func (s *GameState) replay(n int) {
+	copy(s.PlayerGrid, s.HistoryBase)
+	s.cover = coverCount{}
+	s.Dead, s.Won = false, false
+	for _, m := range s.History[:n] {
+		s.apply(m)
+	}
+	s.HistoryPos = n
+}
+
+/*
+Position returns a copy of the game as it was after the first n
+recorded moves. Only moves that are currently applied can be
+replayed, and games that have no recorded history have no
+positions to return.
+*/
+func (s *GameState) Position(n int) (*GameState, bool) {
+	if s.HistoryBase == nil || n < 0 || n > s.HistoryPos {
+		return nil, false
+	}
+	p := &GameState{
+		GameParams:  s.GameParams,
+		Grid:        s.Grid,
+		PlayerGrid:  slices.Clone(s.HistoryBase),
+		HistoryBase: s.HistoryBase,
+		History:     s.History[:s.HistoryPos],
+	}
+	p.replay(n)
+	return p, true
+}
+
+/*
+PlayedGrid returns the player grid as the player's own moves left
+it, without anything RevealPlayerGrid has shown since: on a lost
+game, only the mines that went off.
+*/
+func (s *GameState) PlayedGrid() Grid {
+	if p, ok := s.Position(s.HistoryPos); ok {
+		return p.PlayerGrid
+	}
+	start, err := newGameFromLayout(s.GameParams, s.Grid, s.StartX, s.StartY)
+	if err != nil {
+		return nil
+	}
+	return start.PlayerGrid
+}
@@ -0,0 +1,155 @@
+package mines
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestUndoRedo(t *testing.T) {
+	/*
+	 * . . . *
+	 * . . . .
+	 * * . . .
+	 */
+	game := newTestGame(4, []bool{
+		false, false, false, true,
+		false, false, false, false,
+		true, false, false, false,
+	})
+	game.OpenCell(1, 1)
+	start := slices.Clone(game.PlayerGrid)
+
+	game.Do(Move{Kind: MoveFlag, X: 0, Y: 2})
+	flagged := slices.Clone(game.PlayerGrid)
+
+	game.Do(Move{Kind: MoveOpen, X: 3, Y: 0})
+	if !game.Dead {
+		t.Fatal("opening a mine should kill the player")
+	}
+	if !game.CanUndo() || game.CanRedo() {
+		t.Fatal("a fatal click should be undoable and not redoable")
+	}
+
+	if !game.Undo() {
+		t.Fatal("could not undo the fatal click")
+	}
+	if game.Dead || !slices.Equal(game.PlayerGrid, flagged) {
+		t.Fatalf("undo did not restore the position:\n%s", game.PlayerGrid.ToString(4))
+	}
+	if !game.UsedUndo {
+		t.Error("undo was not recorded on the game")
+	}
+
+	if !game.Undo() || !slices.Equal(game.PlayerGrid, start) {
+		t.Fatalf("undo did not restore the start:\n%s", game.PlayerGrid.ToString(4))
+	}
+	if game.Undo() {
+		t.Error("undo past the start of the history")
+	}
+
+	if !game.Redo() || !slices.Equal(game.PlayerGrid, flagged) {
+		t.Fatalf("redo did not restore the flag:\n%s", game.PlayerGrid.ToString(4))
+	}
+
+	/* A new move discards the undone fatal click. */
+	game.Do(Move{Kind: MoveOpen, X: 3, Y: 2})
+	if game.CanRedo() || len(game.History) != 2 {
+		t.Errorf("new move did not truncate the history: %v", game.History)
+	}
+
+	/* Moves that change nothing are not recorded. */
+	game.Do(Move{Kind: MoveFlag, X: 1, Y: 1})
+	if len(game.History) != 2 {
+		t.Errorf("no-op move was recorded: %v", game.History)
+	}
+}
+
+func TestUndoFatalClickAfterReveal(t *testing.T) {
+	game := newTestGame(3, []bool{false, false, true})
+	game.Do(Move{Kind: MoveOpen, X: 2, Y: 0})
+	if !game.Dead || game.Finished() {
+		t.Fatal("a fatal click that can be undone should not finish the game")
+	}
+
+	/* Once the mines are shown there is no taking the click back. */
+	game.RevealPlayerGrid()
+	if game.CanUndo() || !game.Finished() {
+		t.Errorf("a revealed loss should not be undoable:\n%s", game.PlayerGrid.ToString(3))
+	}
+}
+
+func TestUndoAfterForfeit(t *testing.T) {
+	game := newTestGame(3, []bool{false, false, true})
+	game.Do(Move{Kind: MoveFlag, X: 2, Y: 0})
+	game.RevealPlayerGrid()
+	if game.CanUndo() {
+		t.Error("a forfeited game should not be undoable")
+	}
+}
+
+func TestPlayedGrid(t *testing.T) {
+	/*
+	 * . . * *
+	 * . . . .
+	 */
+	newGame := func() *GameState {
+		game, err := newGameFromLayout(
+			GameParams{Width: 4, Height: 2, MineCount: 2},
+			[]int8{0, 0, 1, 1, 0, 0, 0, 0}, 0, 0,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return game
+	}
+
+	game := newGame()
+	start := slices.Clone(game.PlayerGrid)
+	game.RevealPlayerGrid()
+	if !slices.Equal(game.PlayedGrid(), start) {
+		t.Errorf("expected the start position:\n%s", game.PlayedGrid().ToString(4))
+	}
+
+	game = newGame()
+	game.Do(Move{Kind: MoveOpen, X: 2, Y: 0})
+	game.RevealPlayerGrid()
+	if got := game.PlayedGrid(); got[2] != ExplodedMine || got[3] != Unknown {
+		t.Errorf("expected only the mine that went off:\n%s", got.ToString(4))
+	}
+}
+
+func TestClickStats(t *testing.T) {
+	/*
+	 * * 1 . .
+	 * 1 1 1 1
+	 * . . 1 *
+	 */
+	game, err := newGameFromLayout(
+		GameParams{Width: 4, Height: 3, MineCount: 2},
+		[]int8{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, 1, 0,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	game.Do(Move{Kind: MoveChord, X: 1, Y: 0}) /* unsatisfied */
+	game.Do(Move{Kind: MoveFlag, X: 0, Y: 0})
+	game.Do(Move{Kind: MoveOpen, X: 1, Y: 0}) /* already open */
+	game.Do(Move{Kind: MoveChord, X: 1, Y: 0})
+	game.Do(Move{Kind: MoveOpen, X: 0, Y: 2})
+	game.Do(Move{Kind: MoveOpen, X: 3, Y: 2}) /* game is over */
+
+	if !game.Won {
+		t.Fatalf("game should be won:\n%s", game.PlayerGrid.ToString(4))
+	}
+	want := ClickStats{Left: 3, Right: 1, Chord: 2, Wasted: 2}
+	if game.Clicks != want {
+		t.Fatalf("expected %+v, got %+v", want, game.Clicks)
+	}
+
+	wantEff := Efficiency{BBBVPerSecond: 0.5, IOE: 1.0 / 3, Correctness: 2.0 / 3, FlagAccuracy: 1}
+	if e := game.Efficiency(4 * time.Second); e != wantEff {
+		t.Errorf("expected %+v, got %+v", wantEff, e)
+	}
+}
@@ -0,0 +1,141 @@
+package mines
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrLayoutNeedsGuess = errors.New("layout cannot be solved without guessing")
+	ErrLayoutTooHard    = errors.New("layout needs harder techniques than its grade allows")
+)
+
+/*
+Check that a layout fits the parameters: the right number of
+mines, no square holding more than it can, and none in the start
+square.
+*/
+func (p GameParams) checkLayout(grid []int8, x, y int) error {
+	if len(grid) != p.Width*p.Height {
+		return fmt.Errorf("layout has %d squares, expected %d", len(grid), p.Width*p.Height)
+	}
+	if grid[y*p.Width+x] > 0 {
+		return fmt.Errorf("initial square contains a mine")
+	}
+
+	mineCount := 0
+	for _, n := range grid {
+		if n < 0 || int(n) > p.PerCell() {
+			return fmt.Errorf(
+				"layout has %d mines in a square, expected at most %d",
+				n, p.PerCell(),
+			)
+		}
+		mineCount += int(n)
+	}
+	if mineCount != p.MineCount {
+		return fmt.Errorf("layout has %d mines, expected %d", mineCount, p.MineCount)
+	}
+	return nil
+}
+
+/*
+ParseLayout reads a hand-made board. The first line gives the
+game's seed and the square it starts from, as in the text format:
+
+	5:4:3:1 0,0
+
+and the mine layout follows in the same shape as there, a row to a
+line, with "." for a square with no mines in, "*" for a square with
+one, and the number of mines for a square with more.
+
+The params are checked against limits (see [GameParams.ValidateLayout])
+before any of the layout is read.
+*/
+func ParseLayout(text string, limits Limits) (params GameParams, grid []int8, x, y int, err error) {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	header := strings.Fields(lines[0])
+	if len(header) != 2 {
+		return params, nil, 0, 0, fmt.Errorf(`invalid header "%s"`, lines[0])
+	}
+	p, err := ParseGameSeed(header[0])
+	if err != nil {
+		return params, nil, 0, 0, err
+	}
+	if err := p.ValidateLayout(limits); err != nil {
+		return params, nil, 0, 0, err
+	}
+	params = *p
+	if n, err := fmt.Sscanf(header[1], "%d,%d", &x, &y); n != 2 || err != nil ||
+		!params.PointInBounds(x, y) {
+		return params, nil, 0, 0, fmt.Errorf(`invalid start square "%s"`, header[1])
+	}
+
+	lines = lines[1:]
+	if len(lines) != params.Height {
+		return params, nil, 0, 0, fmt.Errorf(
+			"mine layout has %d rows, expected %d", len(lines), params.Height,
+		)
+	}
+	rows, err := (&GameState{GameParams: params}).readRows(lines)
+	if err != nil {
+		return params, nil, 0, 0, err
+	}
+	grid = make([]int8, 0, len(rows))
+	for _, tok := range rows {
+		n, err := parseLayoutText(tok, params.PerCell())
+		if err != nil {
+			return params, nil, 0, 0, err
+		}
+		grid = append(grid, n)
+	}
+	return params, grid, x, y, nil
+}
+
+/*
+NewGameFromLayout starts a game on a hand-made layout, opening its
+start square, and checks it with the solver the way a unique board
+is checked when it is generated. It returns the techniques the
+solver needed, which include [TechniqueGuess] if it got stuck.
+
+A layout that claims to be unique must be solvable without guessing,
+and one that gives a maximum grade must not need anything harder;
+otherwise the game is returned all the same, along with
+[ErrLayoutNeedsGuess] or [ErrLayoutTooHard], so that the caller can
+show where the solver stopped.
+
+Like a game from a description, the game is marked as having a
+custom layout.
+*/
+func NewGameFromLayout(params GameParams, grid []int8, x, y int) (*GameState, Technique, error) {
+	if err := params.validate(Limits{}, false); err != nil {
+		return nil, 0, err
+	}
+	if !params.PointInBounds(x, y) {
+		return nil, 0, fmt.Errorf("initial square is off the board")
+	}
+	if err := params.checkLayout(grid, x, y); err != nil {
+		return nil, 0, err
+	}
+
+	techniques, err := params.techniques(grid, x, y)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	state, err := newGameFromLayout(params, grid, x, y)
+	if err != nil {
+		return nil, 0, err
+	}
+	state.CustomLayout = true
+
+	switch {
+	case params.Unique && techniques&TechniqueGuess != 0:
+		err = ErrLayoutNeedsGuess
+	case params.MaxGrade != GradeAny && techniques.Grade() > params.MaxGrade:
+		err = ErrLayoutTooHard
+	}
+	return state, techniques, err
+}
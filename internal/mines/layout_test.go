@@ -0,0 +1,92 @@
+package mines
+
+import (
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestParseLayout(t *testing.T) {
+	params, grid, x, y, err := ParseLayout("4:2:3:0:multi2 1,1\n* . . 2\n. . . .\n", Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := GameParams{Width: 4, Height: 2, MineCount: 3, MinesPerCell: 2}
+	if params != want || x != 1 || y != 1 {
+		t.Errorf("expected %+v from 1,1, got %+v from %d,%d", want, params, x, y)
+	}
+	if !slices.Equal(grid, []int8{1, 0, 0, 2, 0, 0, 0, 0}) {
+		t.Errorf("unexpected layout %v", grid)
+	}
+
+	for _, text := range []string{
+		"4:2:3:0 1,1\n* . .\n. . . .\n",
+		"4:2:3:0 1,1\n* . . .\n",
+		"4:2:3:0 4,1\n* . . .\n. . . .\n",
+		"4:2:3:0\n* . . .\n. . . .\n",
+		"4:2:3:0 1,1\n* . . 2\n. . . .\n",
+	} {
+		if _, _, _, _, err := ParseLayout(text, Limits{}); err == nil {
+			t.Errorf("expected an error parsing %q", text)
+		}
+	}
+}
+
+func TestParseLayoutLimits(t *testing.T) {
+	limits := Limits{MaxWidth: 30, MaxHeight: 16, MaxSquares: 480}
+	for _, text := range []string{
+		"31:1:1:0 0,0\n*\n",
+		"17179869184:1:1:0 0,0\n*\n",
+		"4294967296:4294967296:1:0 0,0\n*\n",
+	} {
+		var paramsErr ParamsError
+		if _, _, _, _, err := ParseLayout(text, limits); !errors.As(err, &paramsErr) {
+			t.Errorf("expected the size of %q to be refused, got %v", text, err)
+		}
+	}
+}
+
+func TestNewGameFromLayout(t *testing.T) {
+	params := GameParams{Width: 9, Height: 9, MineCount: 10, Unique: true}
+	generated, err := NewGame(params, 4, 4, rand.New(rand.NewPCG(5, 6)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	game, techniques, err := NewGameFromLayout(params, generated.Grid, 4, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if techniques&TechniqueGuess != 0 {
+		t.Errorf("generated unique board needs guessing: %v", techniques)
+	}
+	if !game.CustomLayout || !slices.Equal(game.PlayerGrid, generated.PlayerGrid) {
+		t.Error("imported game does not match the generated one")
+	}
+
+	_, _, err = NewGameFromLayout(params, generated.Grid[1:], 4, 4)
+	if err == nil {
+		t.Error("expected an error for a short layout")
+	}
+}
+
+func TestNewGameFromLayoutNeedsGuess(t *testing.T) {
+	params, grid, x, y, err := ParseLayout("4:1:2:1 1,0\n* . . *\n", Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	game, techniques, err := NewGameFromLayout(params, grid, x, y)
+	if !errors.Is(err, ErrLayoutNeedsGuess) {
+		t.Fatalf("expected %v, got %v", ErrLayoutNeedsGuess, err)
+	}
+	if game == nil || techniques&TechniqueGuess == 0 {
+		t.Errorf("expected the game and a guess, got %v", techniques)
+	}
+
+	params.Unique = false
+	if _, _, err := NewGameFromLayout(params, grid, x, y); err != nil {
+		t.Errorf("a layout that doesn't claim to be unique was refused: %v", err)
+	}
+}
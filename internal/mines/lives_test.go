@@ -0,0 +1,82 @@
+package mines
+
+import "testing"
+
+func TestLives(t *testing.T) {
+	game := newTestGame(6, []bool{false, false, true, false, true, false})
+	game.Lives = 1
+
+	game.Do(Move{Kind: MoveOpen, X: 2, Y: 0})
+	if game.Dead || game.PlayerGrid[2] != ExplodedMine || game.LivesLeft() != 0 {
+		t.Fatalf("expected to survive the first mine:\n%s", game.Text(false))
+	}
+
+	game.Do(Move{Kind: MoveOpen, X: 2, Y: 0})
+	if game.Dead || game.HistoryPos != 1 {
+		t.Fatal("a mine that has gone off went off again")
+	}
+
+	game.Do(Move{Kind: MoveOpen, X: 4, Y: 0})
+	if !game.Dead {
+		t.Fatalf("expected the second mine to end the game:\n%s", game.Text(false))
+	}
+
+	game.Undo()
+	game.Undo()
+	if game.Dead || game.LivesLeft() != 1 {
+		t.Errorf("undo did not give back the lives, %d left", game.LivesLeft())
+	}
+}
+
+func TestLivesGiveUp(t *testing.T) {
+	game := newTestGame(6, []bool{false, false, true, false, true, false})
+	game.Lives = 1
+
+	game.Do(Move{Kind: MoveOpen, X: 2, Y: 0})
+	if !game.CanUndo() {
+		t.Fatal("expected to be able to undo the first mine")
+	}
+
+	/* Giving up, or running out of time, is not undone by an undo. */
+	game.RevealPlayerGrid()
+	if !game.Dead || game.CanUndo() {
+		t.Fatalf("expected a game given up with lives left to stay over:\n%s", game.Text(false))
+	}
+}
+
+func TestLivesWin(t *testing.T) {
+	game := newTestGame(3, []bool{false, true, false, false, false, false})
+	game.Lives = 1
+
+	game.OpenCell(1, 0)
+	game.OpenCell(0, 0)
+	if game.PlayerGrid[0] != 1 {
+		t.Fatalf("expected a 1 at 0:0, got %v", game.PlayerGrid[0])
+	}
+
+	/* The mine that went off counts towards the number. */
+	game.ChordCell(0, 0)
+	if !game.PlayerGrid[3].IsOpen() || !game.PlayerGrid[4].IsOpen() {
+		t.Fatalf("chord did not open round the mine:\n%s", game.Text(false))
+	}
+	game.OpenCell(2, 0)
+	game.OpenCell(2, 1)
+	if !game.Won || game.Dead || game.Explosions() != 1 {
+		t.Fatalf("expected a win with one explosion:\n%s", game.Text(false))
+	}
+}
+
+func TestLivesHint(t *testing.T) {
+	game := newTestGame(4, []bool{true, false, false, true})
+	game.Lives = 1
+	game.OpenCell(0, 0)
+	game.OpenCell(1, 0)
+
+	hint, err := game.Hint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hint == nil || *hint != (Hint{X: 2, Y: 0}) {
+		t.Fatalf("expected safe square at 2:0, got %+v", hint)
+	}
+}
@@ -6,3 +6,8 @@ func absDiff(x, y int) int {
 	}
 	return y - x
 }
+
+/* The remainder of x divided by n, in the range [0, n). */
+func mod(x, n int) int {
+	return ((x % n) + n) % n
+}
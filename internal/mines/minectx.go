@@ -6,21 +6,56 @@ import (
 )
 
 type mineCtx struct {
-	grid             []bool
-	width, height    int
+	grid []int8
+	topology
+	perCell          int /* most mines a square can hold */
 	sx, sy           int
 	allowBigPerturbs bool
+
+	/*
+	 * Solving a player's position must not move the mines, so
+	 * such callers disable perturbation altogether and may ask to
+	 * be told about every square the solver manages to deduce.
+	 */
+	noPerturb bool
+	onKnown   func(i int, mine bool)
+
+	/*
+	 * Grading a board solves it with only some of the solver's
+	 * techniques; zero allows them all.
+	 */
+	allow Technique
+
+	/*
+	 * With tracing on, mineSolve records each deduction it makes,
+	 * and knownCells the squares each one proves.
+	 */
+	tracing bool
+	steps   []Step
+	proved  []Hint
+}
+
+func (ctx mineCtx) allows(t Technique) bool {
+	return ctx.allow == 0 || ctx.allow&t != 0
 }
 
 func (ctx mineCtx) MineAt(x, y int) bool {
-	return ctx.grid[y*ctx.width+x]
+	return ctx.grid[y*ctx.width+x] > 0
+}
+
+/*
+Return the number of mines in a square. The solver only asks this
+of squares it has already proved to be mines, and it can only
+prove a square to be a mine by working out exactly how many mines
+it holds, so this tells it nothing it doesn't know.
+*/
+func (ctx mineCtx) count(x, y int) int {
+	return int(ctx.grid[y*ctx.width+x])
 }
 
 func (ctx mineCtx) Mines() (count int) {
 	for _, s := range ctx.grid {
-		if s {
-			count++
-		}
+		count += int(s)
 	}
 	return
 }
@@ -30,20 +65,9 @@ func (ctx mineCtx) Open(x, y int) CellState {
 		return Flagged /* *bang* */
 	}
 	n := 0
-	for i := -1; i <= 1; i++ {
-		if x+i < 0 || x+i >= ctx.width {
-			continue
-		}
-		for j := -1; j <= 1; j++ {
-			if y+j < 0 || y+j >= ctx.height {
-				continue
-			}
-			if i == 0 && j == 0 {
-				continue
-			}
-			if ctx.MineAt(x+i, y+j) {
-				n++
-			}
+	for _, d := range ctx.neighbours(y) {
+		if xx, yy, ok := ctx.normalize(x+d.dx, y+d.dy); ok {
+			n += ctx.count(xx, yy)
 		}
 	}
 	return CellState(n)
@@ -56,7 +80,9 @@ func (ctx mineCtx) PrintGrid() string {
 			var ch string
 			if x == ctx.sx && y == ctx.sy {
 				ch = "S "
-			} else if ctx.grid[y*ctx.width+x] {
+			} else if n := ctx.grid[y*ctx.width+x]; n > 1 {
+				ch = fmt.Sprintf("%d ", n)
+			} else if n > 0 {
 				ch = "* "
 			} else {
 				ch = "- "
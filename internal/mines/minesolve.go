@@ -57,7 +57,7 @@ func mineSolve(
 	ctx *mineCtx,
 	r *rand.Rand,
 ) (solveResult, error) {
-	ss := newSetStore()
+	ss := newSetStore(ctx.topology)
 	nperturbs := 0
 
 	/*
@@ -108,20 +108,14 @@ func mineSolve(
 				 * Empty square. Construct the set of non-known squares
 				 * around this one, and determine its mine count.
 				 */
-				var (
-					bit uint16 = 1
-					val uint16 = 0
-				)
-				for dy := -1; dy <= +1; dy++ {
-					for dx := -1; dx <= +1; dx++ {
-						if x+dx < 0 || x+dx >= w || y+dy < 0 || y+dy >= h {
-							/* ignore this one */
-						} else if grid[i+dy*w+dx] == Flagged {
-							mines--
-						} else if grid[i+dy*w+dx] == Unknown {
-							val |= bit
-						}
-						bit <<= 1
+				var val uint16 = 0
+				for _, d := range ctx.neighbours(y) {
+					if xx, yy, ok := ctx.normalize(x+d.dx, y+d.dy); !ok {
+						/* ignore this one */
+					} else if grid[yy*w+xx] == Flagged {
+						mines -= CellState(ctx.count(xx, yy))
+					} else if grid[yy*w+xx] == Unknown {
+						val |= d.bit()
 					}
 				}
 				if val != 0 {
@@ -141,14 +135,14 @@ func mineSolve(
 					 * Compute the mask for this set minus the
 					 * newly known square.
 					 */
-					newmask := setMunge(s.x, s.y, s.mask, x, y, 1, true)
+					newmask := ss.munge(s.x, s.y, s.mask, x, y, 1, true)
 
 					/*
 					 * Compute the new mine count.
 					 */
 					newmines := s.mines
 					if grid[i] == Flagged {
-						newmines--
+						newmines -= ctx.count(x, y)
 					}
 
 					/*
@@ -181,9 +175,14 @@ func mineSolve(
 		if s := ss.todo(); s != nil {
 			/*
 			 * Firstly, see if this set has a mine count of zero or
-			 * of its own cardinality.
+			 * of its own cardinality (times the number of mines a
+			 * square can hold). Where a square can hold several
+			 * mines, a set of just one square also tells us
+			 * exactly what is in it.
 			 */
-			if s.mines == 0 || s.mines == bits.OnesCount16(s.mask) {
+			card := bits.OnesCount16(s.mask)
+			if s.mines == 0 || s.mines == ctx.perCell*card ||
+				card == 1 {
 				/*
 				 * If so, we can immediately mark all the squares
 				 * in the set as known.
@@ -192,6 +191,12 @@ func mineSolve(
 				if err != nil {
 					return NA, err
 				}
+				if ctx.tracing {
+					ctx.trace(Step{
+						Kind: StepSingle,
+						Sets: []TraceSet{ctx.traceSet(s.x, s.y, s.mask, s.mines)},
+					})
+				}
 
 				/*
 				 * Having done that, we need do nothing further
@@ -207,7 +212,10 @@ func mineSolve(
 			 * Failing that, we now search through all the sets
 			 * which overlap this one.
 			 */
-			list := ss.overlap(s.x, s.y, s.mask)
+			var list []*set
+			if ctx.allows(TechniqueOverlap) {
+				list = ss.overlap(s.x, s.y, s.mask)
+			}
 
 			for _, s2 := range list {
 				/*
@@ -219,23 +227,26 @@ func mineSolve(
 				 * sets. The `s wing' is s-s2; the `s2 wing' is
 				 * s2-s.
 				 */
-				swing := setMunge(s.x, s.y, s.mask, s2.x, s2.y, s2.mask, true)
-				s2wing := setMunge(s2.x, s2.y, s2.mask, s.x, s.y, s.mask, true)
+				swing := ss.munge(s.x, s.y, s.mask, s2.x, s2.y, s2.mask, true)
+				s2wing := ss.munge(s2.x, s2.y, s2.mask, s.x, s.y, s.mask, true)
 				swc := bits.OnesCount16(swing)
 				s2wc := bits.OnesCount16(s2wing)
 
 				/*
 				 * If one set has more mines than the other, and
 				 * the number of extra mines is equal to the
-				 * cardinality of that set's wing, then we can mark
+				 * cardinality of that set's wing (times the number
+				 * of mines a square can hold), then we can mark
 				 * every square in the wing as a known mine, and
 				 * every square in the other wing as known clear.
 				 */
-				if (swc == s.mines-s2.mines) || (s2wc == s2.mines-s.mines) {
+				sfull := ctx.perCell*swc == s.mines-s2.mines
+				s2full := ctx.perCell*s2wc == s2.mines-s.mines
+				if sfull || s2full {
 					err := grid.knownCells(
 						w, std, ctx,
 						s.x, s.y, swing,
-						(swc == s.mines-s2.mines),
+						sfull,
 					)
 					if err != nil {
 						return NA, err
@@ -243,11 +254,20 @@ func mineSolve(
 					err = grid.knownCells(
 						w, std, ctx,
 						s2.x, s2.y, s2wing,
-						(s2wc == s2.mines-s.mines),
+						s2full,
 					)
 					if err != nil {
 						return NA, err
 					}
+					if ctx.tracing {
+						ctx.trace(Step{
+							Kind: StepWing,
+							Sets: []TraceSet{
+								ctx.traceSet(s.x, s.y, s.mask, s.mines),
+								ctx.traceSet(s2.x, s2.y, s2.mask, s2.mines),
+							},
+						})
+					}
 					continue
 				}
 
@@ -258,12 +278,35 @@ func mineSolve(
 				 * complement, even if neither smaller set ends up
 				 * being immediately clearable.
 				 */
+				nsets := ss.sets.Count()
+				var derived TraceSet
 				if swc == 0 && s2wc != 0 {
 					/* s is a subset of s2. */
 					ss.add(s2.x, s2.y, s2wing, s2.mines-s.mines)
+					if ctx.tracing {
+						derived = ctx.traceSet(s2.x, s2.y, s2wing, s2.mines-s.mines)
+					}
 				} else if s2wc == 0 && swc != 0 {
 					/* s2 is a subset of s. */
 					ss.add(s.x, s.y, swing, s.mines-s2.mines)
+					if ctx.tracing {
+						derived = ctx.traceSet(s.x, s.y, swing, s.mines-s2.mines)
+					}
+				}
+
+				/*
+				 * Only a set we didn't already have is worth
+				 * mentioning in a trace.
+				 */
+				if ctx.tracing && ss.sets.Count() > nsets {
+					ctx.trace(Step{
+						Kind: StepSubset,
+						Sets: []TraceSet{
+							ctx.traceSet(s.x, s.y, s.mask, s.mines),
+							ctx.traceSet(s2.x, s2.y, s2.mask, s2.mines),
+						},
+						Derived: &derived,
+					})
 				}
 			}
 
@@ -273,7 +316,7 @@ func mineSolve(
 			 * our to-do list.
 			 */
 			doneSomething = true
-		} else if n >= 0 {
+		} else if n >= 0 && ctx.allows(TechniqueCount) {
 			/*
 			 * We have nothing left on our todo list, which means
 			 * all localised deductions have failed. Our next step
@@ -297,7 +340,7 @@ func mineSolve(
 			minesleft := n
 			for i := range w * h {
 				if grid[i] == Flagged {
-					minesleft--
+					minesleft -= ctx.count(i%w, i/w)
 				} else if grid[i] == Unknown {
 					squaresleft++
 				}
@@ -316,7 +359,7 @@ func mineSolve(
 			 * left, or if there are exactly as many mines left as
 			 * squares to play them in, then it's all easy.
 			 */
-			if minesleft == 0 || minesleft == squaresleft {
+			if minesleft == 0 || minesleft == ctx.perCell*squaresleft {
 				for i := range w * h {
 					if grid[i] == Unknown {
 						err := grid.knownCells(
@@ -328,6 +371,13 @@ func mineSolve(
 						}
 					}
 				}
+				if ctx.tracing {
+					ctx.trace(Step{
+						Kind:        StepCount,
+						SquaresLeft: squaresleft,
+						MinesLeft:   minesleft,
+					})
+				}
 				continue /* now go back to main deductive loop */
 			}
 
@@ -402,7 +452,7 @@ func mineSolve(
 
 						/* See if any existing set overlaps this one. */
 						for i := range cursor {
-							if setused[i] && setMunge(
+							if setused[i] && ss.munge(
 								sets[cursor].x,
 								sets[cursor].y,
 								sets[cursor].mask,
@@ -431,7 +481,7 @@ func mineSolve(
 						 * anything interesting.
 						 */
 						if squaresleft > 0 &&
-							(minesleft == 0 || minesleft == squaresleft) {
+							(minesleft == 0 || minesleft == ctx.perCell*squaresleft) {
 							/*
 							 * We have! There is at least one
 							 * square not contained within the set
@@ -450,7 +500,7 @@ func mineSolve(
 									x := i % w
 									for j := range nsets {
 										if setused[j] &&
-											setMunge(
+											ss.munge(
 												sets[j].x, sets[j].y,
 												sets[j].mask,
 												x, y, 1, false,
@@ -470,6 +520,22 @@ func mineSolve(
 									}
 								}
 							}
+							if ctx.tracing {
+								step := Step{
+									Kind:        StepCount,
+									SquaresLeft: squaresleft,
+									MinesLeft:   minesleft,
+								}
+								for j := range nsets {
+									if setused[j] {
+										step.Sets = append(step.Sets, ctx.traceSet(
+											sets[j].x, sets[j].y,
+											sets[j].mask, sets[j].mines,
+										))
+									}
+								}
+								ctx.trace(step)
+							}
 							doneSomething = true
 							break /* return to main deductive loop */
 						}
@@ -519,6 +585,9 @@ func mineSolve(
 		 * a perturb function and ask it to modify the grid to
 		 * make it easier.
 		 */
+		if ctx.noPerturb {
+			break
+		}
 		nperturbs++
 		var changes []*perturbChange
 		var err error
@@ -538,6 +607,9 @@ func mineSolve(
 		if err != nil {
 			return NA, err
 		}
+		if ctx.tracing {
+			ctx.trace(Step{Kind: StepPerturb})
+		}
 		if len(changes) > 0 {
 			/*
 			 * A number of squares have been fiddled with, and
@@ -548,9 +620,16 @@ func mineSolve(
 			 * known non-mine, put it back on the squares-to-do
 			 * list.
 			 */
+			queued := make(map[int]bool)
 			for _, c := range changes {
-				if c.delta < 0 && grid[c.y*w+c.x] != Unknown {
-					std.add(c.y*w + c.x)
+				/*
+				 * A square that holds several mines can turn up
+				 * in more than one change, but mustn't go on the
+				 * list twice.
+				 */
+				if i := c.y*w + c.x; c.delta < 0 && grid[i] != Unknown && !queued[i] {
+					std.add(i)
+					queued[i] = true
 				}
 
 				list := ss.overlap(c.x, c.y, 1)
@@ -0,0 +1,122 @@
+package mines
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func newMultiMineTestGame(width, perCell int, grid []int8) *GameState {
+	game := newTestGame(width, make([]bool, len(grid)))
+	game.Grid = grid
+	game.MinesPerCell = perCell
+	for _, n := range grid {
+		game.MineCount += int(n)
+	}
+	return game
+}
+
+func TestMultiMineNumbers(t *testing.T) {
+	/*
+	 * 3 . .
+	 * . . .
+	 * . . 2
+	 */
+	game := newMultiMineTestGame(3, 3, []int8{3, 0, 0, 0, 0, 0, 0, 0, 2})
+
+	game.OpenCell(1, 1)
+	if game.PlayerGrid[4] != 5 {
+		t.Fatalf("expected 5, got %v:\n%s", game.PlayerGrid[4], game.PlayerGrid.ToString(3))
+	}
+	if game.Won {
+		t.Fatal("game won with squares still to open")
+	}
+
+	for _, i := range []int{1, 2, 3, 5, 6, 7} {
+		game.OpenCell(i%3, i/3)
+	}
+	if !game.Won {
+		t.Fatalf("game should be won:\n%s", game.PlayerGrid.ToString(3))
+	}
+}
+
+func TestMultiMineFlagCycle(t *testing.T) {
+	game := newMultiMineTestGame(2, 3, []int8{3, 0})
+
+	want := []CellState{Flagged, FlagOf(2), FlagOf(3), Unknown}
+	for _, w := range want {
+		game.FlagCell(0, 0)
+		if game.PlayerGrid[0] != w {
+			t.Fatalf("expected %v, got %v", w, game.PlayerGrid[0])
+		}
+	}
+
+	game.FlagCell(0, 0)
+	game.FlagCell(0, 0)
+	game.OpenCell(1, 0)
+	game.RevealPlayerGrid()
+	if game.PlayerGrid[0] != FalselyFlagged {
+		t.Fatalf("a flag for 2 mines on 3 should be false, got %v", game.PlayerGrid[0])
+	}
+}
+
+func TestMultiMineDescriptionRoundTrip(t *testing.T) {
+	params := GameParams{Width: 9, Height: 9, MineCount: 20, MinesPerCell: 3}
+	game, err := NewGame(params, 4, 4, rand.New(rand.NewPCG(1, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desc := game.Description()
+	if err := params.ValidateDesc(desc); err != nil {
+		t.Fatalf("%q: %v", desc, err)
+	}
+	replay, err := NewGameFromDesc(params, desc, -1, -1)
+	if err != nil {
+		t.Fatalf("%q: %v", desc, err)
+	}
+	if !slices.Equal(replay.Grid, game.Grid) {
+		t.Errorf("%q does not describe the original game", desc)
+	}
+
+	if slices.Max(game.Grid) < 2 {
+		t.Fatal("no square holds more than one mine")
+	}
+	params.MinesPerCell = 1
+	if _, err := NewGameFromDesc(params, desc, -1, -1); err == nil {
+		t.Errorf("%q accepted with one mine allowed to a square", desc)
+	}
+}
+
+func TestMultiMineProbabilities(t *testing.T) {
+	game := newMultiMineTestGame(2, 2, []int8{2, 0})
+	if _, err := game.MineProbabilities(); err != ErrProbabilitiesMultiMine {
+		t.Fatalf("expected %v, got %v", ErrProbabilitiesMultiMine, err)
+	}
+}
+
+func TestDecodeLegacyGameState(t *testing.T) {
+	legacy := legacyGameState{
+		Grid:       []bool{true, false, false, true},
+		PlayerGrid: Grid{Unknown, 2, Unknown, Unknown},
+		GameParams: GameParams{Width: 2, Height: 2, MineCount: 2},
+		StartX:     1,
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	game, err := DecodeGameState(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(game.Grid, []int8{1, 0, 0, 1}) {
+		t.Errorf("expected grid [1 0 0 1], got %v", game.Grid)
+	}
+	if !slices.Equal(game.PlayerGrid, legacy.PlayerGrid) || game.StartX != 1 {
+		t.Errorf("decoded state does not match: %+v", game)
+	}
+}
@@ -146,7 +146,7 @@ func (ctx *mineCtx) perturb(
 			 * If this square is too near the starting point,
 			 * don't put it on the list at all.
 			 */
-			if absDiff(y, ctx.sy) <= 1 && absDiff(x, ctx.sx) <= 1 {
+			if ctx.near(ctx.sx, ctx.sy, x, y) {
 				continue
 			}
 
@@ -154,10 +154,11 @@ func (ctx *mineCtx) perturb(
 			 * If this square is in the input set, also don't put
 			 * it on the list!
 			 */
+			dx, dy := ctx.delta(setX, setY, x, y)
 			if (mask == 0 && (*grid)[y*ctx.width+x] == Unknown) ||
-				(x >= setX && x < setX+3 &&
-					y >= setY && y < setY+3 &&
-					mask&(1<<((y-setY)*3+(x-setX))) != 0) {
+				(dx >= 0 && dx < 3 &&
+					dy >= 0 && dy < 3 &&
+					mask&(1<<(dy*3+dx)) != 0) {
 				continue
 			}
 
@@ -173,14 +174,11 @@ func (ctx *mineCtx) perturb(
 				 */
 				sq.priority = mildlyInteresting
 
-				for dy := -1; dy <= +1; dy++ {
-					for dx := -1; dx <= +1; dx++ {
-						if x+dx >= 0 && x+dx < ctx.width &&
-							y+dy >= 0 && y+dy < ctx.height &&
-							(*grid)[(y+dy)*ctx.width+(x+dx)] != Unknown {
-							sq.priority = verySuspicious
-							break
-						}
+				for _, d := range ctx.neighbours(y) {
+					if xx, yy, ok := ctx.normalize(x+d.dx, y+d.dy); ok &&
+						(*grid)[yy*ctx.width+xx] != Unknown {
+						sq.priority = verySuspicious
+						break
 					}
 				}
 			}
@@ -198,7 +196,8 @@ func (ctx *mineCtx) perturb(
 
 	/*
 	 * Now count up the number of full and empty squares in the set
-	 * we've been provided.
+	 * we've been provided. Where a square can hold several mines,
+	 * count the mines in the set and the room for more instead.
 	 */
 	nfull, nempty := 0, 0
 	if mask != 0 {
@@ -207,15 +206,13 @@ func (ctx *mineCtx) perturb(
 				if mask&(1<<(dy*3+dx)) != 0 {
 					// assert(setx+dx <= ctx->w);
 					// assert(sety+dy <= ctx->h);
-					if setX+dx > ctx.width || setY+dy > ctx.height {
+					x, y, ok := ctx.normalize(setX+dx, setY+dy)
+					if !ok {
 						Log.Error("out of range", "dx", dx, "dy", dy, "ctx", ctx)
 						return nil, AssertionError{"out of range"}
 					}
-					if ctx.MineAt(setX+dx, setY+dy) {
-						nfull++
-					} else {
-						nempty++
-					}
+					nfull += ctx.count(x, y)
+					nempty += ctx.perCell - ctx.count(x, y)
 				}
 			}
 		}
@@ -223,11 +220,8 @@ func (ctx *mineCtx) perturb(
 		for y := range ctx.height {
 			for x := range ctx.width {
 				if (*grid)[y*ctx.width+x] == Unknown {
-					if ctx.MineAt(x, y) {
-						nfull++
-					} else {
-						nempty++
-					}
+					nfull += ctx.count(x, y)
+					nempty += ctx.perCell - ctx.count(x, y)
 				}
 			}
 		}
@@ -242,20 +236,28 @@ func (ctx *mineCtx) perturb(
 	 */
 	var toFill, toEmpty []*perturbCell
 	if mask != 0 {
-		toFill = make([]*perturbCell, 0, 9)
-		toEmpty = make([]*perturbCell, 0, 9)
+		toFill = make([]*perturbCell, 0, 9*ctx.perCell)
+		toEmpty = make([]*perturbCell, 0, 9*ctx.perCell)
 	} else {
-		toFill = make([]*perturbCell, 0, ctx.width*ctx.height)
-		toEmpty = make([]*perturbCell, 0, ctx.width*ctx.height)
+		toFill = make([]*perturbCell, 0, ctx.width*ctx.height*ctx.perCell)
+		toEmpty = make([]*perturbCell, 0, ctx.width*ctx.height*ctx.perCell)
 	}
+walk:
 	for _, sq := range squares {
-		if ctx.MineAt(sq.x, sq.y) {
-			toEmpty = append(toEmpty, sq)
-		} else {
-			toFill = append(toFill, sq)
-		}
-		if len(toFill) == nfull || len(toEmpty) == nempty {
-			break
+		/*
+		 * A square can give up each of its mines, and take one
+		 * more for each mine it has room for.
+		 */
+		n := ctx.count(sq.x, sq.y)
+		for k := range ctx.perCell {
+			if k < n {
+				toEmpty = append(toEmpty, sq)
+			} else {
+				toFill = append(toFill, sq)
+			}
+			if len(toFill) == nfull || len(toEmpty) == nempty {
+				break walk
+			}
 		}
 	}
 
@@ -287,12 +289,13 @@ func (ctx *mineCtx) perturb(
 					if mask&(1<<(dy*3+dx)) != 0 {
 						// assert(setx+dx <= ctx->w);
 						// assert(sety+dy <= ctx->h);
-						if setX+dx > ctx.width || setY+dy > ctx.height {
+						x, y, ok := ctx.normalize(setX+dx, setY+dy)
+						if !ok {
 							Log.Error("out of range", "dx", dx, "dy", dy, "ctx", ctx)
 							return nil, AssertionError{"out of range"}
 						}
-						if !ctx.MineAt(setX+dx, setY+dy) {
-							setlist = append(setlist, (setY+dy)*ctx.width+(setX+dx))
+						for range ctx.perCell - ctx.count(x, y) {
+							setlist = append(setlist, y*ctx.width+x)
 						}
 					}
 				}
@@ -301,7 +304,7 @@ func (ctx *mineCtx) perturb(
 			for y := range ctx.height {
 				for x := range ctx.width {
 					if (*grid)[y*ctx.width+x] == Unknown {
-						if !ctx.MineAt(x, y) {
+						for range ctx.perCell - ctx.count(x, y) {
 							setlist = append(setlist, y*ctx.width+x)
 						}
 					}
@@ -369,6 +372,23 @@ func (ctx *mineCtx) perturb(
 		})
 	}
 
+	/*
+	 * Empty or fill a square of the set, one mine at a time.
+	 */
+	setChanges := func(x, y int) {
+		units := ctx.count(x, y)
+		if dSet == perturbPlaceMine {
+			units = ctx.perCell - units
+		}
+		for range units {
+			changes = append(changes, &perturbChange{
+				x:     x,
+				y:     y,
+				delta: dSet,
+			})
+		}
+	}
+
 	if setlist != nil {
 		// assert(todo == toempty)
 		if !reflect.DeepEqual(todos, toEmpty) {
@@ -388,19 +408,8 @@ func (ctx *mineCtx) perturb(
 		for dy := range 3 {
 			for dx := range 3 {
 				if mask&(1<<(dy*3+dx)) != 0 {
-					var currval perturbDelta
-					if ctx.MineAt(setX+dx, setY+dy) {
-						currval = perturbPlaceMine
-					} else {
-						currval = perturbClearMine
-					}
-					if dSet == -currval {
-						changes = append(changes, &perturbChange{
-							x:     setX + dx,
-							y:     setY + dy,
-							delta: dSet,
-						})
-					}
+					x, y, _ := ctx.normalize(setX+dx, setY+dy)
+					setChanges(x, y)
 				}
 			}
 		}
@@ -408,19 +417,7 @@ func (ctx *mineCtx) perturb(
 		for y := range ctx.height {
 			for x := range ctx.width {
 				if (*grid)[y*ctx.width+x] == Unknown {
-					var currval perturbDelta
-					if ctx.MineAt(x, y) {
-						currval = perturbPlaceMine
-					} else {
-						currval = perturbClearMine
-					}
-					if dSet == -currval {
-						changes = append(changes, &perturbChange{
-							x:     x,
-							y:     y,
-							delta: dSet,
-						})
-					}
+					setChanges(x, y)
 				}
 			}
 		}
@@ -447,56 +444,41 @@ func (ctx *mineCtx) perturb(
 		)
 
 		/*
-		 * Check we're not trying to add an existing mine or remove
-		 * an absent one.
+		 * Check we're not trying to add a mine to a full square or
+		 * remove an absent one.
 		 */
 		// assert((delta < 0) ^ (ctx->grid[y*ctx->w+x] == 0))
-		if delta.PlacesMine() == ctx.MineAt(x, y) {
-			Log.Error("trying to add an existing mine or remove an absent one",
-				"change", c, "mine", ctx.MineAt(x, y))
-			return nil, AssertionError{"trying to add an existing mine or remove an absent one"}
+		if n := ctx.count(x, y); delta.PlacesMine() && n == ctx.perCell ||
+			!delta.PlacesMine() && n == 0 {
+			Log.Error("trying to add a mine to a full square or remove an absent one",
+				"change", c, "mines", n)
+			return nil, AssertionError{"trying to add a mine to a full square or remove an absent one"}
 		}
 
 		/*
 		 * Actually make the change.
 		 */
-		ctx.grid[y*ctx.width+x] = delta.PlacesMine()
+		ctx.grid[y*ctx.width+x] += int8(delta)
 
 		/*
 		 * Update any numbers already present in the grid.
 		 */
-		for dy := -1; dy <= +1; dy++ {
-			for dx := -1; dx <= +1; dx++ {
-				if x+dx >= 0 && x+dx < ctx.width &&
-					y+dy >= 0 && y+dy < ctx.height &&
-					(*grid)[(y+dy)*ctx.width+(x+dx)] != Unknown {
-					if dx == 0 && dy == 0 {
-						/*
-						 * The square itself is marked as known in
-						 * the grid. Mark it as a mine if it's a
-						 * mine, or else work out its number.
-						 */
-						if delta == perturbPlaceMine {
-							(*grid)[y*ctx.width+x] = Flagged
-						} else {
-							var minecount CellState = 0
-							for dy2 := -1; dy2 <= +1; dy2++ {
-								for dx2 := -1; dx2 <= +1; dx2++ {
-									if x+dx2 >= 0 && x+dx2 < ctx.width &&
-										y+dy2 >= 0 && y+dy2 < ctx.height &&
-										ctx.MineAt(x+dx2, y+dy2) {
-										minecount++
-									}
-								}
-							}
-							(*grid)[y*ctx.width+x] = minecount
-						}
-					} else {
-						if (*grid)[(y+dy)*ctx.width+(x+dx)] >= 0 {
-							(*grid)[(y+dy)*ctx.width+(x+dx)] += CellState(delta)
-						}
-					}
-				}
+		if (*grid)[y*ctx.width+x] != Unknown {
+			/*
+			 * The square itself is marked as known in the grid.
+			 * Mark it as a mine if it's a mine, or else work out
+			 * its number.
+			 */
+			if ctx.MineAt(x, y) {
+				(*grid)[y*ctx.width+x] = Flagged
+			} else {
+				(*grid)[y*ctx.width+x] = ctx.Open(x, y)
+			}
+		}
+		for _, d := range ctx.neighbours(y) {
+			xx, yy, ok := ctx.normalize(x+d.dx, y+d.dy)
+			if ok && (*grid)[yy*ctx.width+xx] >= 0 {
+				(*grid)[yy*ctx.width+xx] += CellState(delta)
 			}
 		}
 	}
@@ -0,0 +1,24 @@
+// source: https://git.tartarus.org/simon/puzzles.git/mines.c
+
+package mines
+
+import "slices"
+
+type Preset struct {
+	Name string
+	GameParams
+}
+
+/*
+The presets game_fetch_preset offers, less the denser variants of
+each size.
+*/
+var defaultPresets = []Preset{
+	{"beginner", GameParams{Width: 9, Height: 9, MineCount: 10, Unique: true}},
+	{"intermediate", GameParams{Width: 16, Height: 16, MineCount: 40, Unique: true}},
+	{"expert", GameParams{Width: 30, Height: 16, MineCount: 99, Unique: true}},
+}
+
+func DefaultPresets() []Preset {
+	return slices.Clone(defaultPresets)
+}
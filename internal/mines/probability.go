@@ -0,0 +1,523 @@
+package mines
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"slices"
+)
+
+/*
+A constraint says that exactly `mines' of the listed squares are
+mines. Squares are indices into the grid.
+*/
+type constraint struct {
+	cells []int
+	mines int
+}
+
+/*
+Expand a set's (x,y,mask) description into grid indices.
+*/
+func (s *set) cells(t topology) []int {
+	cells := make([]int, 0, bits.OnesCount16(s.mask))
+	for b := range 9 {
+		if s.mask&(1<<b) != 0 {
+			x, y, _ := t.normalize(s.x+b%3, s.y+b/3)
+			cells = append(cells, y*t.width+x)
+		}
+	}
+	return cells
+}
+
+/*
+Build the sets the solver would start from: one for each open
+square with unknown neighbours, less any mines known to be next
+to it.
+*/
+func constraints(t topology, grid Grid) ([]constraint, error) {
+	ss := newSetStore(t)
+	for y := range t.height {
+		for x := range t.width {
+			i := y*t.width + x
+			if grid[i] < 0 {
+				continue
+			}
+			var val uint16 = 0
+			mines := int(grid[i])
+			for _, d := range t.neighbours(y) {
+				xx, yy, ok := t.normalize(x+d.dx, y+d.dy)
+				if !ok {
+					continue
+				}
+				switch grid[yy*t.width+xx] {
+				case Unknown:
+					val |= d.bit()
+				case Flagged:
+					mines--
+				}
+			}
+			if val != 0 {
+				if err := ss.add(x-1, y-1, val, mines); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	cs := make([]constraint, 0, ss.sets.Count())
+	for i := range ss.sets.Count() {
+		s := ss.sets.Index(i)
+		cs = append(cs, constraint{cells: s.cells(t), mines: s.mines})
+	}
+	return cs, nil
+}
+
+/*
+A group of squares linked together by constraints, and the states
+its layouts pass through as its squares are decided one at a time.
+*/
+type component struct {
+	cells       []int
+	constraints []constraint
+	ways        []*big.Int /* layouts with k mines, for every k */
+
+	member [][]int /* constraints each square takes part in */
+	after  [][]int /* squares of each of those left to decide */
+	active [][]int /* for each layer, constraints on both sides */
+	layers [][]edge
+	states []int /* number of states in each layer */
+}
+
+/*
+Split the constrained squares into independent components.
+*/
+func components(cs []constraint) []*component {
+	parent := make(map[int]int)
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for _, c := range cs {
+		for _, i := range c.cells {
+			if _, ok := parent[i]; !ok {
+				parent[i] = i
+			}
+		}
+		for _, i := range c.cells[1:] {
+			parent[find(i)] = find(c.cells[0])
+		}
+	}
+
+	byRoot := make(map[int]*component)
+	var comps []*component
+	for _, c := range cs {
+		root := find(c.cells[0])
+		comp, ok := byRoot[root]
+		if !ok {
+			comp = &component{}
+			byRoot[root] = comp
+			comps = append(comps, comp)
+		}
+		comp.constraints = append(comp.constraints, c)
+	}
+
+	/*
+	 * List each component's squares in the order its constraints
+	 * mention them, so that constraints are completed (and can
+	 * prune the search) as early as possible.
+	 */
+	for _, comp := range comps {
+		seen := make(map[int]bool)
+		for _, c := range comp.constraints {
+			for _, i := range c.cells {
+				if !seen[i] {
+					seen[i] = true
+					comp.cells = append(comp.cells, i)
+				}
+			}
+		}
+	}
+	return comps
+}
+
+/*
+A layer's edges lead from each of its states to those of the next,
+deciding one more square.
+*/
+type edge struct {
+	from, to int
+	mine     bool
+}
+
+/*
+The most work, in numbers kept for every state of every layer, that
+weighing a position may take. Boards whose open numbers leave too
+many ways of satisfying them are refused rather than tying up the
+server.
+*/
+const maxProbabilityWork = 1 << 20
+
+var ErrProbabilitiesTooComplex = errors.New(
+	"position has too many possible layouts to weigh",
+)
+
+/*
+Work out the states of the component. Once the first j squares are
+decided, all that matters for the rest is how many mines went into
+each constraint that still has squares on both sides, so layouts
+that agree on those counts are counted together, and the cost goes
+with the number of such states rather than the number of layouts.
+*/
+func (comp *component) build() error {
+	n := len(comp.cells)
+	local := make(map[int]int, n)
+	for j, i := range comp.cells {
+		local[i] = j
+	}
+
+	first := make([]int, len(comp.constraints))
+	last := make([]int, len(comp.constraints))
+	comp.member = make([][]int, n)
+	comp.after = make([][]int, n)
+	for k, c := range comp.constraints {
+		first[k], last[k] = n, -1
+		for _, i := range c.cells {
+			j := local[i]
+			first[k], last[k] = min(first[k], j), max(last[k], j)
+			comp.member[j] = append(comp.member[j], k)
+		}
+	}
+	for j, ks := range comp.member {
+		for _, k := range ks {
+			left := 0
+			for _, i := range comp.constraints[k].cells {
+				if local[i] > j {
+					left++
+				}
+			}
+			comp.after[j] = append(comp.after[j], left)
+		}
+	}
+	comp.active = make([][]int, n+1)
+	for k := range comp.constraints {
+		for j := first[k] + 1; j <= last[k]; j++ {
+			comp.active[j] = append(comp.active[j], k)
+		}
+	}
+
+	/*
+	 * A state is the mines placed in each of the layer's active
+	 * constraints, one byte apiece.
+	 */
+	placed := make([]int, len(comp.constraints))
+	keys := []string{""}
+	comp.states = []int{1}
+	comp.layers = make([][]edge, n)
+	work := 0
+	for j := range n {
+		next := make(map[string]int)
+		var nextKeys []string
+		at := make([]int, len(comp.member[j]))
+		for t, k := range comp.member[j] {
+			at[t] = slices.Index(comp.active[j], k) /* -1 if k starts here */
+		}
+		for from, key := range keys {
+			for a, k := range comp.active[j] {
+				placed[k] = int(key[a])
+			}
+			for _, mine := range []bool{false, true} {
+				ok := true
+				for t, k := range comp.member[j] {
+					p := 0
+					if at[t] >= 0 {
+						p = int(key[at[t]])
+					}
+					if mine {
+						p++
+					}
+					placed[k] = p
+					target := comp.constraints[k].mines
+					if p > target || p+comp.after[j][t] < target {
+						ok = false
+					}
+				}
+				if !ok {
+					continue
+				}
+				b := make([]byte, len(comp.active[j+1]))
+				for a, k := range comp.active[j+1] {
+					b[a] = byte(placed[k])
+				}
+				to, seen := next[string(b)]
+				if !seen {
+					to = len(nextKeys)
+					next[string(b)] = to
+					nextKeys = append(nextKeys, string(b))
+				}
+				comp.layers[j] = append(comp.layers[j], edge{from, to, mine})
+			}
+		}
+		keys = nextKeys
+		comp.states = append(comp.states, len(keys))
+		work += len(keys) * (j + 2)
+		if work > maxProbabilityWork {
+			return ErrProbabilitiesTooComplex
+		}
+	}
+	if len(keys) != 1 {
+		return fmt.Errorf("component has no consistent layout")
+	}
+	comp.ways = comp.forward(nil, nil)
+	return nil
+}
+
+/*
+Run through the layers counting, for every state, the ways of
+reaching it with each number of mines, and return the counts for
+the whole component. Given the weight of every way of finishing
+from each state (see [component.backward]), it also adds up into
+mineWeights the weight of the layouts with a mine in each square.
+*/
+func (comp *component) forward(finish [][][]*big.Int, mineWeights []*big.Int) []*big.Int {
+	var t big.Int
+	counts := [][]*big.Int{{big.NewInt(1)}}
+	for j, edges := range comp.layers {
+		next := make([][]*big.Int, comp.states[j+1])
+		for s := range next {
+			next[s] = make([]*big.Int, j+2)
+			for a := range next[s] {
+				next[s][a] = new(big.Int)
+			}
+		}
+		for _, e := range edges {
+			d := 0
+			if e.mine {
+				d = 1
+			}
+			for a, n := range counts[e.from] {
+				next[e.to][a+d].Add(next[e.to][a+d], n)
+				if e.mine && finish != nil {
+					mineWeights[j].Add(mineWeights[j], t.Mul(n, finish[j+1][e.to][a+1]))
+				}
+			}
+		}
+		counts = next
+	}
+	return counts[0]
+}
+
+/*
+Run back through the layers working out, for every state and every
+number of mines placed before it, the total weight of the ways of
+finishing the component from there, where a layout with k mines in
+all weighs weight[k].
+*/
+func (comp *component) backward(weight []*big.Int) [][][]*big.Int {
+	n := len(comp.cells)
+	finish := make([][][]*big.Int, n+1)
+	finish[n] = [][]*big.Int{weight}
+	for j := n - 1; j >= 0; j-- {
+		finish[j] = make([][]*big.Int, comp.states[j])
+		for s := range finish[j] {
+			finish[j][s] = make([]*big.Int, j+1)
+			for a := range finish[j][s] {
+				finish[j][s][a] = new(big.Int)
+			}
+		}
+		for _, e := range comp.layers[j] {
+			d := 0
+			if e.mine {
+				d = 1
+			}
+			for a, n := range finish[j][e.from] {
+				n.Add(n, finish[j+1][e.to][a+d])
+			}
+		}
+	}
+	return finish
+}
+
+/*
+The weight of the layouts with a mine in each square of the
+component, where a layout with k mines in the component weighs
+weight[k].
+*/
+func (comp *component) mineWeights(weight []*big.Int) []*big.Int {
+	w := make([]*big.Int, len(comp.cells))
+	for j := range w {
+		w[j] = new(big.Int)
+	}
+	comp.forward(comp.backward(weight), w)
+	return w
+}
+
+func convolve(a, b []*big.Int) []*big.Int {
+	c := make([]*big.Int, len(a)+len(b)-1)
+	for k := range c {
+		c[k] = new(big.Int)
+	}
+	var t big.Int
+	for i, x := range a {
+		if x.Sign() == 0 {
+			continue
+		}
+		for j, y := range b {
+			c[i+j].Add(c[i+j], t.Mul(x, y))
+		}
+	}
+	return c
+}
+
+var ErrProbabilitiesMultiMine = errors.New(
+	"mine probabilities are only available with one mine to a square",
+)
+
+/*
+MineProbabilities returns, for every square, the exact probability
+that it holds a mine given only what the player can see: the open
+numbers and the total mine count. Flags and question marks are
+ignored, open squares have probability zero, and mines that have
+gone off have probability one.
+
+The open numbers are turned into the solver's sets, every layout
+of the constrained squares consistent with them is counted, and
+the layouts are weighted by the number of ways of placing the
+remaining mines among the unconstrained squares. Positions that
+would take too long to count give [ErrProbabilitiesTooComplex].
+*/
+func (s *GameState) MineProbabilities() ([]float64, error) {
+	if s.PerCell() > 1 {
+		return nil, ErrProbabilitiesMultiMine
+	}
+	grid := s.knowledge()
+
+	/* Mines that have gone off are no longer anywhere to be placed. */
+	mineCount := s.MineCount
+	for _, c := range grid {
+		if c == Flagged {
+			mineCount--
+		}
+	}
+
+	cs, err := constraints(s.topology(), grid)
+	if err != nil {
+		return nil, err
+	}
+	comps := components(cs)
+	for _, comp := range comps {
+		if err := comp.build(); err != nil {
+			return nil, err
+		}
+	}
+
+	constrained := make(map[int]bool)
+	for _, comp := range comps {
+		for _, i := range comp.cells {
+			constrained[i] = true
+		}
+	}
+	unconstrained := 0
+	for i, c := range grid {
+		if c == Unknown && !constrained[i] {
+			unconstrained++
+		}
+	}
+
+	/*
+	 * weight(k) is the number of ways of placing the mines that
+	 * are not among the constrained squares, given that k of them
+	 * are.
+	 */
+	weight := func(k int) *big.Int {
+		rest := mineCount - k
+		if rest < 0 || rest > unconstrained {
+			return new(big.Int)
+		}
+		return new(big.Int).Binomial(int64(unconstrained), int64(rest))
+	}
+
+	/*
+	 * convolved[c] is the number of layouts of every component
+	 * except c, by mine count.
+	 */
+	all := []*big.Int{big.NewInt(1)}
+	convolved := make([][]*big.Int, len(comps))
+	for c := range comps {
+		others := []*big.Int{big.NewInt(1)}
+		for c2, comp := range comps {
+			if c2 != c {
+				others = convolve(others, comp.ways)
+			}
+		}
+		convolved[c] = others
+		all = convolve(all, comps[c].ways)
+	}
+
+	total := new(big.Int)
+	var t big.Int
+	for k, n := range all {
+		total.Add(total, t.Mul(n, weight(k)))
+	}
+	if total.Sign() == 0 {
+		return nil, fmt.Errorf("no mine layout is consistent with the player grid")
+	}
+
+	probability := func(n *big.Int) float64 {
+		p, _ := new(big.Rat).SetFrac(n, total).Float64()
+		return p
+	}
+
+	probs := make([]float64, len(grid))
+
+	for c, comp := range comps {
+		/*
+		 * For every possible number of mines in this component,
+		 * the number of ways of completing the rest of the grid.
+		 */
+		rest := make([]*big.Int, len(comp.ways))
+		for k := range rest {
+			rest[k] = new(big.Int)
+			for k2, n := range convolved[c] {
+				rest[k].Add(rest[k], t.Mul(n, weight(k+k2)))
+			}
+		}
+		for j, n := range comp.mineWeights(rest) {
+			probs[comp.cells[j]] = probability(n)
+		}
+	}
+
+	if unconstrained > 0 {
+		/*
+		 * Each unconstrained square is a mine in C(u-1, m-k-1) of
+		 * the C(u, m-k) ways of filling them with m-k mines.
+		 */
+		n := new(big.Int)
+		for k, ways := range all {
+			rest := mineCount - k
+			if rest < 1 || rest > unconstrained {
+				continue
+			}
+			n.Add(n, t.Mul(ways, new(big.Int).Binomial(int64(unconstrained-1), int64(rest-1))))
+		}
+		p := probability(n)
+		for i, c := range grid {
+			if c == Unknown && !constrained[i] {
+				probs[i] = p
+			}
+		}
+	}
+
+	/* Squares the player knows to be mines are certainly mines. */
+	for i, c := range grid {
+		if c == Flagged {
+			probs[i] = 1
+		}
+	}
+
+	return probs, nil
+}
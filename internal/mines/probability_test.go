@@ -0,0 +1,172 @@
+package mines
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMineProbabilities(t *testing.T) {
+	/*
+	 * Opening the square in the middle of the top row shows a 1
+	 * with two unknown squares next to it, either of which could
+	 * be the mine. With one mine in total, the third unknown
+	 * square must be safe.
+	 *
+	 * * 1 .
+	 * . . .
+	 */
+	game := newTestGame(3, []bool{
+		true, false, false,
+		false, false, false,
+	})
+	game.OpenCell(1, 0)
+	game.OpenCell(2, 0)
+	game.OpenCell(2, 1)
+
+	probs, err := game.MineProbabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []float64{
+		0.5, 0, 0,
+		0.5, 0, 0,
+	}
+	for i := range expected {
+		if math.Abs(probs[i]-expected[i]) > 1e-9 {
+			t.Errorf("square %d: expected %v, got %v", i, expected[i], probs[i])
+		}
+	}
+}
+
+func TestMineProbabilitiesGlobalCount(t *testing.T) {
+	/*
+	 * The 1 at 0:0 puts exactly one mine in {1:0, 0:1, 1:1}, so
+	 * the remaining mine is somewhere among the other five
+	 * unknown squares. Layouts of the three constrained squares
+	 * are weighted equally, each leaving C(5,1) ways to place the
+	 * other mine.
+	 */
+	game := newTestGame(3, []bool{
+		false, true, false,
+		false, false, false,
+		false, false, true,
+	})
+	game.OpenCell(0, 0)
+
+	probs, err := game.MineProbabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{1, 3, 4} {
+		if math.Abs(probs[i]-1.0/3) > 1e-9 {
+			t.Errorf("square %d: expected 1/3, got %v", i, probs[i])
+		}
+	}
+	for _, i := range []int{2, 5, 6, 7, 8} {
+		if math.Abs(probs[i]-1.0/5) > 1e-9 {
+			t.Errorf("square %d: expected 1/5, got %v", i, probs[i])
+		}
+	}
+
+	sum := 0.0
+	for _, p := range probs {
+		sum += p
+	}
+	if math.Abs(sum-float64(game.MineCount)) > 1e-9 {
+		t.Errorf("probabilities should add up to the mine count, got %v", sum)
+	}
+}
+
+func TestMineProbabilitiesLongFrontier(t *testing.T) {
+	/*
+	 * A strip whose middle row is open and all 1s, with a mine
+	 * in every third square of the top row. Every layout of the
+	 * top and bottom rows that fits the 1s is as likely as any
+	 * other, and there are far too many of them to count one by
+	 * one.
+	 */
+	const width = 98
+	mines := make([]bool, 3*width)
+	for x := 1; x < width; x += 3 {
+		mines[x] = true
+	}
+	game := newTestGame(width, mines)
+	for x := range width {
+		game.OpenCell(x, 1)
+	}
+
+	probs, err := game.MineProbabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := 0.0
+	for _, p := range probs {
+		sum += p
+	}
+	if math.Abs(sum-float64(game.MineCount)) > 1e-6 {
+		t.Errorf("probabilities should add up to the mine count, got %v", sum)
+	}
+}
+
+func TestMineProbabilitiesTooComplex(t *testing.T) {
+	/*
+	 * Every other column open, next to columns of mines laid out
+	 * like a checkerboard, leaves far too many states to weigh.
+	 */
+	const width, height = 31, 30
+	mines := make([]bool, width*height)
+	for i := range mines {
+		x, y := i%width, i/width
+		mines[i] = x%2 == 0 && (x/2+y)%2 == 0
+	}
+	game := newTestGame(width, mines)
+	for x := 1; x < width; x += 2 {
+		for y := range height {
+			game.OpenCell(x, y)
+		}
+	}
+
+	if _, err := game.MineProbabilities(); err != ErrProbabilitiesTooComplex {
+		t.Errorf("expected %v, got %v", ErrProbabilitiesTooComplex, err)
+	}
+}
+
+func TestMineProbabilitiesKnownMine(t *testing.T) {
+	game := newTestGame(4, []bool{true, false, false, true})
+	game.Lives = 1
+	game.OpenCell(1, 0)
+	game.OpenCell(0, 0)
+
+	probs, err := game.MineProbabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if probs[0] != 1 {
+		t.Errorf("a mine that went off should be certain, got %v", probs[0])
+	}
+}
+
+func TestMineProbabilitiesLives(t *testing.T) {
+	/*
+	 * * 1 . *
+	 *
+	 * Once the mine at 0:0 has gone off, the 1 next to it is
+	 * accounted for, so 2:0 is safe and the other mine must be
+	 * at 3:0.
+	 */
+	game := newTestGame(4, []bool{true, false, false, true})
+	game.Lives = 1
+	game.OpenCell(1, 0)
+	game.OpenCell(0, 0)
+
+	probs, err := game.MineProbabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []float64{1, 0, 0, 1}
+	for i := range expected {
+		if math.Abs(probs[i]-expected[i]) > 1e-9 {
+			t.Errorf("square %d: expected %v, got %v", i, expected[i], probs[i])
+		}
+	}
+}
@@ -48,12 +48,14 @@ func setcmp(a, b *set) int {
 type setstore struct {
 	sets                 *tree234.Tree234[set]
 	todo_head, todo_tail *set
+	topology
 }
 
-func newSetStore() *setstore {
+func newSetStore(t topology) *setstore {
 	return &setstore{
 		sets:      tree234.NewTree234(setcmp),
 		todo_head: nil, todo_tail: nil,
+		topology: t,
 	}
 }
 
@@ -90,6 +92,14 @@ func (ss *setstore) add(x, y int, mask uint16, mines int) error {
 		y++
 	}
 
+	/*
+	 * On a torus, also bring the corner back onto the board, so
+	 * that each set has only one name.
+	 */
+	if ss.wrap {
+		x, y, _ = ss.normalize(x, y)
+	}
+
 	/*
 	 * Create a set structure and add it to the tree.
 	 */
@@ -152,6 +162,16 @@ overlap a provided input set.
 func (ss *setstore) overlap(x, y int, mask uint16) (ret []*set) {
 	for xx := x - 3; xx < x+3; xx++ {
 		for yy := y - 3; yy < y+3; yy++ {
+			/*
+			 * Sets three squares up or left can't overlap. On a
+			 * torus they would also wrap round onto the last row
+			 * or column searched, and be found twice.
+			 */
+			if ss.wrap && (xx == x-3 || yy == y-3) {
+				continue
+			}
+			xx, yy, _ := ss.normalize(xx, yy)
+
 			/*
 			 * Find the first set with these top left coordinates.
 			 */
@@ -169,7 +189,7 @@ func (ss *setstore) overlap(x, y int, mask uint16) (ret []*set) {
 					 * really overlap, and add it to the list if
 					 * so.
 					 */
-					if setMunge(x, y, mask, s.x, s.y, s.mask, false) != 0 {
+					if ss.munge(x, y, mask, s.x, s.y, s.mask, false) != 0 {
 						/*
 						 * There's an overlap.
 						 */
@@ -203,6 +223,15 @@ func (ss *setstore) todo() *set {
 	}
 }
 
+/*
+Munge two sets as setMunge does, first moving the second set to
+wherever it is closest to the first on a torus.
+*/
+func (ss *setstore) munge(x1, y1 int, mask1 uint16, x2, y2 int, mask2 uint16, diff bool) uint16 {
+	dx, dy := ss.delta(x1, y1, x2, y2)
+	return setMunge(x1, y1, mask1, x1+dx, y1+dy, mask2, diff)
+}
+
 /*
 Take two input sets, in the form (x,y,mask). Munge the first by
 taking either its intersection with the second or its difference
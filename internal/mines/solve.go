@@ -0,0 +1,63 @@
+package mines
+
+/*
+Run the solver on the player's current position without moving
+any mines, and return every square it manages to prove safe or
+mined, in the order the deductions were made.
+*/
+func (s *GameState) deduce() ([]Hint, error) {
+	var deductions []Hint
+	ctx := &mineCtx{
+		grid:      s.Grid,
+		topology:  s.topology(),
+		perCell:   s.PerCell(),
+		noPerturb: true,
+	}
+	ctx.onKnown = func(i int, mine bool) {
+		deductions = append(deductions, Hint{X: i % s.Width, Y: i / s.Width, Mine: mine})
+	}
+
+	_, err := mineSolve(s.Width, s.Height, s.MineCount, s.knowledge(), ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return deductions, nil
+}
+
+/*
+Open every square the solver can prove safe and flag every
+square it can prove to be a mine.
+*/
+func (s *GameState) solve() error {
+	deductions, err := s.deduce()
+	if err != nil {
+		return err
+	}
+	for _, d := range deductions {
+		if s.Won {
+			break
+		}
+		i := d.Y*s.Width + d.X
+		if d.Mine {
+			s.PlayerGrid[i] = FlagOf(int(s.Grid[i]))
+		} else if s.PlayerGrid[i] < 0 {
+			s.OpenCell(d.X, d.Y)
+		}
+	}
+	return nil
+}
+
+/*
+Solve is a port of solve_game. On a game in progress it reveals
+everything that can be deduced from the current position and
+marks the game as solver-assisted. On a finished game it
+shows the full solution, marking mistakes in the player's flags.
+*/
+func (s *GameState) Solve() error {
+	if s.Dead || s.Won {
+		s.RevealPlayerGrid()
+		return nil
+	}
+	s.UsedSolve = true
+	return s.Do(Move{Kind: MoveSolve})
+}
@@ -0,0 +1,44 @@
+package mines
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestSolveUniqueGame(t *testing.T) {
+	params := GameParams{Width: 9, Height: 9, MineCount: 10, Unique: true}
+	game, err := NewGame(params, 4, 4, rand.New(rand.NewPCG(1, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := game.Solve(); err != nil {
+		t.Fatal(err)
+	}
+	if !game.Won || !game.UsedSolve {
+		t.Fatalf("unique game was not solved:\n%s", game.PlayerGrid.ToString(params.Width))
+	}
+
+	if game.Undo() {
+		t.Error("a won game should not be undoable")
+	}
+}
+
+func TestSolveFinishedGame(t *testing.T) {
+	game := newTestGame(4, []bool{true, false, false, true})
+	game.OpenCell(1, 0)
+	game.FlagCell(2, 0)
+	game.OpenCell(0, 0)
+
+	if err := game.Solve(); err != nil {
+		t.Fatal(err)
+	}
+	expected := Grid{ExplodedMine, 1, FalselyFlagged, UnflaggedMine}
+	if !slices.Equal(game.PlayerGrid, expected) {
+		t.Errorf("expected %v, got %v", expected, game.PlayerGrid)
+	}
+	if game.UsedSolve {
+		t.Error("revealing a finished game should not count as using the solver")
+	}
+}
@@ -0,0 +1,270 @@
+package mines
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+The text format of a game, after game_text_format in mines.c, but
+able to tell every state a square can be in apart, so that a game
+can be read back from it.
+
+The first line gives the game's seed, whether it is being played,
+has been won or is dead, and the square it was started from:
+
+	9:9:10:1 playing 4,4
+
+followed by "question-marks" if flagging cycles through question
+marks. Then comes the board as the player sees it, a row to a line,
+its squares separated by single spaces. Odd rows of a hex board are
+indented by one more space, to show how they are shifted. Squares
+are written as
+
+	"-"           an open square with no mines around it
+	"1" to "56"   an open square with that many mines around it
+	"."           a covered square
+	"?"           a square marked with a question mark
+	"*"           a square flagged as a mine
+	"2*" to "7*"  a square flagged as holding that many mines
+	"F"           a flag shown to be right once the game is over
+	"X"           a flag shown to be wrong once the game is over
+	"M"           a mine shown once the game is over
+	"!"           a mine that went off
+
+The mine layout can follow after a blank line, in the same shape,
+with "." for a square with no mines in, "*" for a square with one,
+and the number of mines for a square with more.
+*/
+func (s *GameState) Text(withLayout bool) string {
+	var b strings.Builder
+
+	status := "playing"
+	if s.Won {
+		status = "won"
+	} else if s.Dead {
+		status = "dead"
+	}
+	fmt.Fprintf(&b, "%s %s %d,%d", s.Seed(), status, s.StartX, s.StartY)
+	if s.QuestionMarks {
+		b.WriteString(" question-marks")
+	}
+	b.WriteString("\n")
+
+	s.writeRows(&b, func(i int) string { return cellText(s.PlayerGrid[i]) })
+	if withLayout {
+		b.WriteString("\n")
+		s.writeRows(&b, func(i int) string { return layoutText(s.Grid[i]) })
+	}
+	return b.String()
+}
+
+func (s *GameState) writeRows(b *strings.Builder, square func(i int) string) {
+	for y := range s.Height {
+		if s.Tiling == Hex && y%2 == 1 {
+			b.WriteString(" ")
+		}
+		for x := range s.Width {
+			if x > 0 {
+				b.WriteString(" ")
+			}
+			b.WriteString(square(y*s.Width + x))
+		}
+		b.WriteString("\n")
+	}
+}
+
+func cellText(c CellState) string {
+	switch {
+	case c == 0:
+		return "-"
+	case c.IsOpen():
+		return strconv.Itoa(int(c))
+	case c == Unknown:
+		return "."
+	case c == Question:
+		return "?"
+	case c == Flagged:
+		return "*"
+	case c.IsFlag():
+		return strconv.Itoa(c.FlagCount()) + "*"
+	case c == CorrectlyFlagged:
+		return "F"
+	case c == FalselyFlagged:
+		return "X"
+	case c == UnflaggedMine:
+		return "M"
+	case c == ExplodedMine:
+		return "!"
+	default:
+		return fmt.Sprintf("<%d>", c) /* never seen outside OpenCell */
+	}
+}
+
+func parseCellText(tok string, perCell int) (CellState, error) {
+	switch tok {
+	case "-":
+		return 0, nil
+	case ".":
+		return Unknown, nil
+	case "?":
+		return Question, nil
+	case "*":
+		return Flagged, nil
+	case "F":
+		return CorrectlyFlagged, nil
+	case "X":
+		return FalselyFlagged, nil
+	case "M":
+		return UnflaggedMine, nil
+	case "!":
+		return ExplodedMine, nil
+	}
+	if n, ok := strings.CutSuffix(tok, "*"); ok {
+		k, err := strconv.Atoi(n)
+		if err != nil || k < 2 || k > perCell {
+			return 0, fmt.Errorf(`invalid flag "%s"`, tok)
+		}
+		return FlagOf(k), nil
+	}
+	n, err := strconv.Atoi(tok)
+	if err != nil || n < 1 || n > 8*perCell {
+		return 0, fmt.Errorf(`invalid square "%s"`, tok)
+	}
+	return CellState(n), nil
+}
+
+func layoutText(n int8) string {
+	switch n {
+	case 0:
+		return "."
+	case 1:
+		return "*"
+	default:
+		return strconv.Itoa(int(n))
+	}
+}
+
+func parseLayoutText(tok string, perCell int) (int8, error) {
+	switch tok {
+	case ".":
+		return 0, nil
+	case "*":
+		return 1, nil
+	}
+	n, err := strconv.Atoi(tok)
+	if err != nil || n < 2 || n > perCell {
+		return 0, fmt.Errorf(`invalid layout square "%s"`, tok)
+	}
+	return int8(n), nil
+}
+
+/*
+ParseText reads a game back from its text format. A game read
+without its mine layout has none, and can be looked at but not
+played.
+*/
+func ParseText(text string) (*GameState, error) {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	header := strings.Fields(lines[0])
+	if len(header) < 3 {
+		return nil, fmt.Errorf(`invalid header "%s"`, lines[0])
+	}
+	params, err := ParseGameSeed(header[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := params.checkMinesPerCell(); err != nil {
+		return nil, err
+	}
+	if params.Width < 1 || params.Height < 1 {
+		return nil, fmt.Errorf("board must have at least one square")
+	}
+	s := &GameState{GameParams: *params}
+	switch header[1] {
+	case "playing":
+	case "won":
+		s.Won = true
+	case "dead":
+		s.Dead = true
+	default:
+		return nil, fmt.Errorf(`invalid game status "%s"`, header[1])
+	}
+	if n, err := fmt.Sscanf(header[2], "%d,%d", &s.StartX, &s.StartY); n != 2 || err != nil ||
+		!s.PointInBounds(s.StartX, s.StartY) {
+		return nil, fmt.Errorf(`invalid start square "%s"`, header[2])
+	}
+	for _, opt := range header[3:] {
+		if opt != "question-marks" {
+			return nil, fmt.Errorf(`unknown option "%s"`, opt)
+		}
+		s.QuestionMarks = true
+	}
+
+	lines = lines[1:]
+	rows, err := s.readRows(lines)
+	if err != nil {
+		return nil, err
+	}
+	s.PlayerGrid = make(Grid, 0, len(rows))
+	for _, tok := range rows {
+		c, err := parseCellText(tok, s.PerCell())
+		if err != nil {
+			return nil, err
+		}
+		s.PlayerGrid = append(s.PlayerGrid, c)
+	}
+
+	lines = lines[s.Height:]
+	if len(lines) == 0 {
+		return s, nil
+	}
+	if lines[0] != "" {
+		return nil, fmt.Errorf("expected a blank line before the mine layout")
+	}
+	lines = lines[1:]
+	if len(lines) != s.Height {
+		return nil, fmt.Errorf("mine layout has %d rows, expected %d", len(lines), s.Height)
+	}
+	rows, err = s.readRows(lines)
+	if err != nil {
+		return nil, err
+	}
+	s.Grid = make([]int8, 0, len(rows))
+	mineCount := 0
+	for _, tok := range rows {
+		n, err := parseLayoutText(tok, s.PerCell())
+		if err != nil {
+			return nil, err
+		}
+		s.Grid = append(s.Grid, n)
+		mineCount += int(n)
+	}
+	if mineCount != s.MineCount {
+		return nil, fmt.Errorf("mine layout has %d mines, expected %d", mineCount, s.MineCount)
+	}
+	return s, nil
+}
+
+/*
+Read the squares of the first Height lines, checking every row is
+the width of the board. The size comes from the header, which may
+claim anything, so nothing is set aside for the squares until the
+rows have shown they are there.
+*/
+func (s *GameState) readRows(lines []string) ([]string, error) {
+	if len(lines) < s.Height {
+		return nil, fmt.Errorf("board has %d rows, expected %d", len(lines), s.Height)
+	}
+	var squares []string
+	for y, line := range lines[:s.Height] {
+		row := strings.Fields(line)
+		if len(row) != s.Width {
+			return nil, fmt.Errorf("row %d has %d squares, expected %d", y, len(row), s.Width)
+		}
+		squares = append(squares, row...)
+	}
+	return squares, nil
+}
@@ -0,0 +1,130 @@
+package mines
+
+import (
+	"flag"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+func TestTextGolden(t *testing.T) {
+	/*
+	 * * 1 - - -
+	 * 1 1 1 2 2
+	 * - - 2 * *
+	 * - - 2 * 3
+	 */
+	game, err := newGameFromLayout(
+		GameParams{Width: 5, Height: 4, MineCount: 4},
+		[]int8{
+			1, 0, 0, 0, 0,
+			0, 0, 0, 0, 0,
+			0, 0, 0, 1, 1,
+			0, 0, 0, 1, 0,
+		}, 0, 3,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.Do(Move{Kind: MoveFlag, X: 3, Y: 2})
+	game.Do(Move{Kind: MoveQuestion, X: 4, Y: 3})
+	checkTextGolden(t, game, "playing.txt")
+
+	game.Do(Move{Kind: MoveFlag, X: 4, Y: 3})
+	game.Do(Move{Kind: MoveOpen, X: 0, Y: 0})
+	game.RevealPlayerGrid()
+	checkTextGolden(t, game, "lost.txt")
+}
+
+func checkTextGolden(t *testing.T, game *GameState, name string) {
+	t.Helper()
+	got := game.Text(true)
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("%s: expected\n%s\ngot\n%s", name, want, got)
+	}
+}
+
+func TestTextRoundTrip(t *testing.T) {
+	tests := []GameParams{
+		{Width: 9, Height: 9, MineCount: 10, Unique: true},
+		{Width: 10, Height: 8, MineCount: 12, Unique: true, Tiling: Hex, Wrap: true},
+		{Width: 9, Height: 9, MineCount: 20, MinesPerCell: 3},
+	}
+	for _, params := range tests {
+		t.Run(params.Seed(), func(t *testing.T) {
+			r := rand.New(rand.NewPCG(1, 2))
+			game, err := NewGame(params, 4, 4, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for !game.Dead && !game.Won {
+				x, y := r.IntN(params.Width), r.IntN(params.Height)
+				kind := []MoveKind{MoveOpen, MoveFlag, MoveFlag, MoveQuestion}[r.IntN(4)]
+				game.Do(Move{Kind: kind, X: x, Y: y})
+				checkTextRoundTrip(t, game)
+			}
+			game.RevealPlayerGrid()
+			checkTextRoundTrip(t, game)
+		})
+	}
+}
+
+func checkTextRoundTrip(t *testing.T, game *GameState) {
+	t.Helper()
+	text := game.Text(true)
+	parsed, err := ParseText(text)
+	if err != nil {
+		t.Fatalf("%v:\n%s", err, text)
+	}
+	if parsed.GameParams != game.GameParams ||
+		parsed.Dead != game.Dead || parsed.Won != game.Won ||
+		parsed.StartX != game.StartX || parsed.StartY != game.StartY ||
+		!slices.Equal(parsed.PlayerGrid, game.PlayerGrid) ||
+		!slices.Equal(parsed.Grid, game.Grid) {
+		t.Fatalf("text does not describe the game:\n%s", text)
+	}
+
+	view, err := ParseText(game.Text(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Grid != nil || !slices.Equal(view.PlayerGrid, game.PlayerGrid) {
+		t.Fatalf("player's view was not read back:\n%s", text)
+	}
+}
+
+func TestParseTextErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"3:1:1:0 playing",
+		"3:1:1:0 paused 0,0\n- 1 .\n",
+		"3:1:1:0 playing 3,0\n- 1 .\n",
+		"3:1:1:0 playing 0,0\n- 1\n",
+		"3:1:1:0 playing 0,0\n- 1 9\n",
+		"3:1:1:0 playing 0,0\n- 1 2*\n",
+		"3:1:1:0 playing 0,0\n- 1 .\n. . *",
+		"3:1:1:0 playing 0,0\n- 1 .\n\n. * *",
+		/* Sizes far beyond the text there is to read. */
+		"17179869184:1:1:0 playing 0,0\n-\n",
+		"4294967296:4294967296:1:0 playing 0,0\n-\n",
+		"1:9223372036854775807:1:0 playing 0,0\n-\n",
+	} {
+		if _, err := ParseText(text); err == nil {
+			t.Errorf("%q was accepted", text)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package mines
+
+import "fmt"
+
+/*
+The shape of the cells the board is tiled with.
+
+A hex board is laid out in rows, with every odd row shifted half a
+cell to the right, so that a cell's six neighbours are the two
+either side of it and two in each of the rows above and below.
+*/
+type Tiling int8
+
+const (
+	Square Tiling = iota
+	Hex
+)
+
+func (t Tiling) String() string {
+	switch t {
+	case Square:
+		return "square"
+	case Hex:
+		return "hex"
+	default:
+		return fmt.Sprintf("Tiling(%d)", int8(t))
+	}
+}
+
+func ParseTiling(s string) (Tiling, error) {
+	switch s {
+	case "square", "":
+		return Square, nil
+	case "hex":
+		return Hex, nil
+	default:
+		return Square, fmt.Errorf(`unknown tiling "%s"`, s)
+	}
+}
+
+func (t Tiling) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *Tiling) UnmarshalText(text []byte) (err error) {
+	*t, err = ParseTiling(string(text))
+	return err
+}
+
+/*
+The offset from a square to one of its neighbours.
+*/
+type offset struct {
+	dx, dy int
+}
+
+var (
+	squareNeighbours = []offset{
+		{-1, -1}, {0, -1}, {+1, -1},
+		{-1, 0}, {+1, 0},
+		{-1, +1}, {0, +1}, {+1, +1},
+	}
+	hexEvenNeighbours = []offset{
+		{-1, -1}, {0, -1},
+		{-1, 0}, {+1, 0},
+		{-1, +1}, {0, +1},
+	}
+	hexOddNeighbours = []offset{
+		{0, -1}, {+1, -1},
+		{-1, 0}, {+1, 0},
+		{0, +1}, {+1, +1},
+	}
+)
+
+/*
+The shape of the board: its size, how it is tiled, and whether its
+edges wrap round to make it a torus.
+
+Every square's neighbours lie within the 3x3 box centred on it, on
+either tiling. The solver relies on this: it describes sets of
+squares as 3x3 bitmasks anchored at their top left corner, and
+compares two sets by the offset between their anchors.
+
+On a torus that offset is only meaningful if no two columns (or
+rows) of a pair of overlapping sets can be the same square, which
+needs the board to be at least five squares across in each
+direction. A hex board must also have an even number of rows, or
+the shifted rows would not line up where the top and bottom edges
+meet.
+*/
+type topology struct {
+	width, height int
+	tiling        Tiling
+	wrap          bool
+}
+
+const minWrapSize = 5
+
+func (p GameParams) topology() topology {
+	return topology{width: p.Width, height: p.Height, tiling: p.Tiling, wrap: p.Wrap}
+}
+
+/*
+Return the offsets of the neighbours of a square in row y.
+*/
+func (t topology) neighbours(y int) []offset {
+	if t.tiling == Hex {
+		if mod(y, 2) == 0 {
+			return hexEvenNeighbours
+		}
+		return hexOddNeighbours
+	}
+	return squareNeighbours
+}
+
+/*
+Map a point that may lie off the edge of the board to the square
+it refers to. On a flat board there is no such square and ok is
+false; on a torus the coordinates wrap round.
+*/
+func (t topology) normalize(x, y int) (nx, ny int, ok bool) {
+	if t.wrap {
+		return mod(x, t.width), mod(y, t.height), true
+	}
+	return x, y, 0 <= x && x < t.width && 0 <= y && y < t.height
+}
+
+/*
+Return the offset from (x1,y1) to (x2,y2). On a torus this is the
+shortest way round, in the range (-size/2, size/2] each way.
+*/
+func (t topology) delta(x1, y1, x2, y2 int) (dx, dy int) {
+	dx, dy = x2-x1, y2-y1
+	if t.wrap {
+		dx = mod(dx+(t.width-1)/2, t.width) - (t.width-1)/2
+		dy = mod(dy+(t.height-1)/2, t.height) - (t.height-1)/2
+	}
+	return dx, dy
+}
+
+/*
+Report whether (x2,y2) is (x1,y1) itself or one of its neighbours.
+*/
+func (t topology) near(x1, y1, x2, y2 int) bool {
+	dx, dy := t.delta(x1, y1, x2, y2)
+	if dx == 0 && dy == 0 {
+		return true
+	}
+	for _, o := range t.neighbours(y1) {
+		if o.dx == dx && o.dy == dy {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Return the bit standing for the neighbour at offset o in the 3x3
+mask of the box centred on a square.
+*/
+func (o offset) bit() uint16 {
+	return 1 << ((o.dy+1)*3 + (o.dx + 1))
+}
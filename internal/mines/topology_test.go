@@ -0,0 +1,110 @@
+package mines
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSeedRoundTrip(t *testing.T) {
+	tests := []struct {
+		seed   string
+		params GameParams
+	}{
+		{"9:9:10:1", GameParams{Width: 9, Height: 9, MineCount: 10, Unique: true}},
+		{"16:16:40:0:wrap", GameParams{Width: 16, Height: 16, MineCount: 40, Wrap: true}},
+		{"9:9:10:1:hex", GameParams{Width: 9, Height: 9, MineCount: 10, Unique: true, Tiling: Hex}},
+		{"8:8:10:1:hex:wrap", GameParams{
+			Width: 8, Height: 8, MineCount: 10, Unique: true, Tiling: Hex, Wrap: true,
+		}},
+		{"9:9:20:1:multi3", GameParams{Width: 9, Height: 9, MineCount: 20, Unique: true, MinesPerCell: 3}},
+		{"9:9:10:1:easy", GameParams{Width: 9, Height: 9, MineCount: 10, Unique: true, MaxGrade: GradeEasy}},
+		{"9:9:10:1:easy:safe", GameParams{Width: 9, Height: 9, MineCount: 10, Unique: true, MaxGrade: GradeEasy, FirstClick: FirstClickSafe}},
+		{"9:9:10:0:none", GameParams{Width: 9, Height: 9, MineCount: 10, FirstClick: FirstClickNone}},
+		{"9:9:10:0:lives3", GameParams{Width: 9, Height: 9, MineCount: 10, Lives: 3}},
+		{"9:9:10:0:lives1:time60", GameParams{Width: 9, Height: 9, MineCount: 10, Lives: 1, TimeLimit: 60}},
+		{"9:9:10:1:wrap:lives1:medium:safe", GameParams{Width: 9, Height: 9, MineCount: 10, Unique: true, Wrap: true, Lives: 1, MaxGrade: GradeMedium, FirstClick: FirstClickSafe}},
+		{"9:9:10:1:chosen", GameParams{Width: 9, Height: 9, MineCount: 10, Unique: true, FirstClick: FirstClickChosen}},
+	}
+	for _, test := range tests {
+		t.Run(test.seed, func(t *testing.T) {
+			p, err := ParseGameSeed(test.seed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *p != test.params {
+				t.Fatalf("expected %+v, got %+v", test.params, *p)
+			}
+			if s := p.Seed(); s != test.seed {
+				t.Fatalf("expected seed %q, got %q", test.seed, s)
+			}
+		})
+	}
+
+	for _, seed := range []string{"9:9:10:1:spiral", "9:9:10:1:multi1", "9:9:10:1:multi8"} {
+		if _, err := ParseGameSeed(seed); err == nil {
+			t.Fatalf("invalid seed %q was accepted", seed)
+		}
+	}
+}
+
+func TestTorusNumbers(t *testing.T) {
+	/*
+	 * * . . . *
+	 * . . . . .
+	 * . . . . .
+	 * . . . . .
+	 * * . . . .
+	 *
+	 * On a torus the corner squares all touch one another.
+	 */
+	grid := make([]bool, 25)
+	grid[0], grid[4], grid[20] = true, true, true
+	game := newTestGame(5, grid)
+	game.Wrap = true
+
+	game.OpenCell(2, 2)
+	if game.PlayerGrid[24] != 3 {
+		t.Fatalf("expected 3 at the far corner, got %v:\n%s",
+			game.PlayerGrid[24], game.PlayerGrid.ToString(5))
+	}
+	if !game.Won {
+		t.Fatalf("game should be won:\n%s", game.PlayerGrid.ToString(5))
+	}
+}
+
+func TestHexNumbers(t *testing.T) {
+	/*
+	 *  . * . .
+	 *   . . . .
+	 *  * . * .
+	 *   . . . .
+	 *
+	 * (1,1) is on a shifted row, so it touches (1,0) and (2,0)
+	 * above it and (1,2) and (2,2) below it, but not (0,2).
+	 */
+	grid := make([]bool, 16)
+	grid[1], grid[8], grid[10] = true, true, true
+	game := newTestGame(4, grid)
+	game.Tiling = Hex
+
+	game.OpenCell(1, 1)
+	if game.PlayerGrid[5] != 2 {
+		t.Fatalf("expected 2, got %v:\n%s", game.PlayerGrid[5], game.PlayerGrid.ToString(4))
+	}
+	game.OpenCell(0, 1)
+	if game.PlayerGrid[4] != 2 {
+		t.Fatalf("expected 2, got %v:\n%s", game.PlayerGrid[4], game.PlayerGrid.ToString(4))
+	}
+}
+
+func TestCannotWrap(t *testing.T) {
+	tests := []GameParams{
+		{Width: 4, Height: 9, MineCount: 3, Wrap: true},
+		{Width: 9, Height: 9, MineCount: 10, Tiling: Hex, Wrap: true},
+	}
+	for _, params := range tests {
+		if _, err := NewGame(params, 0, 0, nil); !errors.Is(err, ErrCannotWrap) {
+			t.Fatalf("%s: expected ErrCannotWrap, got %v", params.Seed(), err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package mines
+
+import (
+	"fmt"
+	"strings"
+)
+
+type StepKind int8
+
+const (
+	/* A set holds no mines, or nothing but mines. */
+	StepSingle StepKind = iota
+
+	/*
+	 * Two sets overlap, and the difference in their mine counts
+	 * fills one wing, leaving the other clear.
+	 */
+	StepWing
+
+	/*
+	 * One set lies inside another, so the rest of the larger one
+	 * holds the difference in their mine counts. This proves no
+	 * squares itself, but gives a new set to reason with.
+	 */
+	StepSubset
+
+	/*
+	 * Once the sets listed are set aside, the mines left on the
+	 * board fill the squares left over, or there are none.
+	 */
+	StepCount
+
+	/* The generator moved mines to get the solver unstuck. */
+	StepPerturb
+)
+
+func (k StepKind) String() string {
+	switch k {
+	case StepSingle:
+		return "single"
+	case StepWing:
+		return "wing"
+	case StepSubset:
+		return "subset"
+	case StepCount:
+		return "count"
+	case StepPerturb:
+		return "perturb"
+	default:
+		return fmt.Sprintf("StepKind(%d)", int8(k))
+	}
+}
+
+type Cell struct {
+	X, Y int
+}
+
+/*
+A set of unknown squares and the number of mines among them, as the
+solver knows it: usually the covered squares round an open number.
+*/
+type TraceSet struct {
+	Squares []Cell
+	Mines   int
+}
+
+/*
+One deduction the solver made, with the sets it combined and the
+squares it proved.
+*/
+type Step struct {
+	Kind StepKind
+	Sets []TraceSet
+
+	Derived *TraceSet /* for StepSubset */
+
+	/* For StepCount, what is left once Sets are set aside. */
+	SquaresLeft, MinesLeft int
+
+	Proved []Hint
+}
+
+func (s Step) String() string {
+	var b strings.Builder
+	b.WriteString(s.Kind.String())
+	for _, set := range s.Sets {
+		fmt.Fprintf(&b, " %v=%d", set.Squares, set.Mines)
+	}
+	if s.Derived != nil {
+		fmt.Fprintf(&b, " => %v=%d", s.Derived.Squares, s.Derived.Mines)
+	}
+	if s.Kind == StepCount {
+		fmt.Fprintf(&b, " left %d in %d", s.MinesLeft, s.SquaresLeft)
+	}
+	for _, h := range s.Proved {
+		if h.Mine {
+			fmt.Fprintf(&b, " %d,%d:mine", h.X, h.Y)
+		} else {
+			fmt.Fprintf(&b, " %d,%d:safe", h.X, h.Y)
+		}
+	}
+	return b.String()
+}
+
+func (ctx *mineCtx) traceSet(x, y int, mask uint16, mines int) TraceSet {
+	t := TraceSet{Mines: mines}
+	for i := range 9 {
+		if mask&(1<<i) != 0 {
+			sx, sy, _ := ctx.normalize(x+i%3, y+i/3)
+			t.Squares = append(t.Squares, Cell{X: sx, Y: sy})
+		}
+	}
+	return t
+}
+
+/*
+Record a step, along with the squares proved since the last one.
+*/
+func (ctx *mineCtx) trace(step Step) {
+	step.Proved, ctx.proved = ctx.proved, nil
+	ctx.steps = append(ctx.steps, step)
+}
+
+/*
+Explain runs the solver on the player's current position and
+returns every deduction it makes, in order. Like a hint, it marks
+a game that is not finished as solver-assisted.
+*/
+func (s *GameState) Explain() ([]Step, error) {
+	if !s.Finished() {
+		s.UsedSolve = true
+	}
+	ctx := &mineCtx{
+		grid:      s.Grid,
+		topology:  s.topology(),
+		perCell:   s.PerCell(),
+		noPerturb: true,
+		tracing:   true,
+	}
+	_, err := mineSolve(s.Width, s.Height, s.MineCount, s.knowledge(), ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.steps, nil
+}
+
+/*
+ExplainSquare returns the steps up to and including the one that
+proves what is in square (x,y). It returns false if the solver
+can't prove it from this position.
+*/
+func ExplainSquare(steps []Step, x, y int) ([]Step, bool) {
+	for i, step := range steps {
+		for _, h := range step.Proved {
+			if h.X == x && h.Y == y {
+				return steps[:i+1], true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,77 @@
+package mines
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	game := newTestGame(4, []bool{false, false, true, false})
+	game.OpenCell(0, 0)
+
+	steps, err := game.Explain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !game.UsedSolve {
+		t.Error("explanation was not recorded on the game")
+	}
+
+	kinds := make([]StepKind, len(steps))
+	for i, s := range steps {
+		kinds[i] = s.Kind
+	}
+	if !slices.Equal(kinds, []StepKind{StepSingle, StepCount}) {
+		t.Fatalf("expected a single and a count step, got %v", steps)
+	}
+	if want := (TraceSet{Squares: []Cell{{2, 0}}, Mines: 1}); !slices.Equal(steps[0].Sets[0].Squares, want.Squares) ||
+		steps[0].Sets[0].Mines != want.Mines {
+		t.Errorf("expected set %v, got %v", want, steps[0].Sets[0])
+	}
+	if want := []Hint{{X: 3, Y: 0}}; !slices.Equal(steps[1].Proved, want) {
+		t.Errorf("expected count step to prove %v, got %v", want, steps[1].Proved)
+	}
+
+	explained, ok := ExplainSquare(steps, 2, 0)
+	if !ok || len(explained) != 1 {
+		t.Errorf("expected one step to prove 2:0, got %v", explained)
+	}
+	if _, ok := ExplainSquare(steps, 0, 0); ok {
+		t.Error("an open square should not need proving")
+	}
+}
+
+/*
+The trace should account for every square the solver proves, in
+the order it proves them.
+*/
+func TestExplainMatchesDeductions(t *testing.T) {
+	params := GameParams{Width: 16, Height: 16, MineCount: 40, Unique: true}
+	game, err := NewGame(params, 8, 8, rand.New(rand.NewPCG(3, 4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deductions, err := game.deduce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps, err := game.Explain()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var proved []Hint
+	for _, s := range steps {
+		proved = append(proved, s.Proved...)
+	}
+	if !slices.Equal(proved, deductions) {
+		t.Fatalf("trace proved %d squares, solver %d", len(proved), len(deductions))
+	}
+	for _, s := range steps {
+		if s.Kind == StepPerturb {
+			t.Fatalf("explaining a position should never perturb: %v", s)
+		}
+	}
+}
@@ -1,9 +1,9 @@
 package repository
 
 import (
-	"bytes"
 	"context"
-	"encoding/gob"
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -26,45 +26,106 @@ type GameSession struct {
 	State         []byte
 	CreatedAt     pgtype.Timestamptz
 	UpdatedAt     pgtype.Timestamptz
+	UsedUndo      bool
+	UsedSolve     bool
+	CustomLayout  bool
+	DailyDate     pgtype.Date
+	DailyPreset   *string
+	Wrap          bool
+	Tiling        string
+	MinesPerCell  int
+	Bbbv          *int /* board statistics, missing on older games */
+	Openings      *int
+	Islands       *int
+	LeftClicks    int
+	RightClicks   int
+	ChordClicks   int
+	WastedClicks  int
+	Grade         *int /* a mines.Grade, missing on older games */
+	FirstClick    string
+	Lives         int
+	TimeLimit     int /* seconds from started_at, or 0 for no limit */
 }
 
 type CreateGameSessionParams struct {
-	PlayerId *int
+	PlayerId    *int
+	DailyDate   *time.Time
+	DailyPreset *string
 }
 
 func (p CreateGameSessionParams) UpdateArgs(args *pgx.NamedArgs) *pgx.NamedArgs {
 	if p.PlayerId != nil {
 		(*args)["player_id"] = *p.PlayerId
 	}
+	if p.DailyDate != nil {
+		(*args)["daily_date"] = *p.DailyDate
+	}
+	if p.DailyPreset != nil {
+		(*args)["daily_preset"] = *p.DailyPreset
+	}
 	return args
 }
 
 func (q Queries) CreateGameSession(
 	ctx context.Context, state *mines.GameState, params CreateGameSessionParams,
 ) (*GameSession, error) {
-	var buf bytes.Buffer
-	if err := gob.NewEncoder(&buf).Encode(state); err != nil {
+	buf, err := state.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	stats := state.BoardStats()
+	techniques, err := state.Techniques()
+	if err != nil {
 		return nil, err
 	}
 
+	/* An unprotected first click can end the game at once. */
+	var endedAt *time.Time
+	if state.Dead || state.Won {
+		now := time.Now().UTC()
+		endedAt = &now
+	}
+
 	args := pgx.NamedArgs{
-		"width":      state.Width,
-		"height":     state.Height,
-		"mine_count": state.MineCount,
-		"unique":     state.Unique,
-		"dead":       state.Dead,
-		"won":        state.Won,
-		"state":      buf.Bytes(),
+		"width":          state.Width,
+		"height":         state.Height,
+		"mine_count":     state.MineCount,
+		"unique":         state.Unique,
+		"wrap":           state.Wrap,
+		"tiling":         state.Tiling.String(),
+		"mines_per_cell": state.PerCell(),
+		"bbbv":           stats.BBBV,
+		"openings":       stats.Openings,
+		"islands":        stats.Islands,
+		"grade":          int(techniques.Grade()),
+		"first_click":    state.FirstClick.String(),
+		"lives":          state.Lives,
+		"time_limit":     state.TimeLimit,
+		"ended_at":       endedAt,
+		"left_clicks":    state.Clicks.Left,
+		"right_clicks":   state.Clicks.Right,
+		"chord_clicks":   state.Clicks.Chord,
+		"wasted_clicks":  state.Clicks.Wasted,
+		"dead":           state.Dead,
+		"won":            state.Won,
+		"state":          buf,
+		"custom_layout":  state.CustomLayout,
 	}
 	params.UpdateArgs(&args)
 
 	rows, _ := q.db.Query(
 		ctx,
 		`INSERT INTO game_session (
-			player_id, width, height, mine_count, "unique", dead, won, state
+			player_id, width, height, mine_count, "unique", dead, won, state,
+			custom_layout, daily_date, daily_preset, wrap, tiling, mines_per_cell,
+			bbbv, openings, islands, grade, first_click, lives, time_limit, ended_at,
+			left_clicks, right_clicks, chord_clicks, wasted_clicks
 		) 
 		VALUES (
-			@player_id, @width, @height, @mine_count, @unique, @dead, @won, @state
+			@player_id, @width, @height, @mine_count, @unique, @dead, @won, @state,
+			@custom_layout, @daily_date, @daily_preset, @wrap, @tiling, @mines_per_cell,
+			@bbbv, @openings, @islands, @grade, @first_click, @lives, @time_limit, @ended_at,
+			@left_clicks, @right_clicks, @chord_clicks, @wasted_clicks
 		) 
 		RETURNING *;`,
 		args,
@@ -84,10 +145,13 @@ func (q Queries) FetchGameSession(ctx context.Context, gameSessionId int) (*Game
 }
 
 type UpdateGameSessionParams struct {
-	Dead    *bool
-	Won     *bool
-	EndedAt *time.Time
-	State   *[]byte
+	Dead      *bool
+	Won       *bool
+	EndedAt   *time.Time
+	State     *[]byte
+	UsedUndo  *bool
+	UsedSolve *bool
+	Clicks    *mines.ClickStats
 }
 
 func (p UpdateGameSessionParams) SetClause() (string, map[string]any) {
@@ -110,19 +174,122 @@ func (p UpdateGameSessionParams) SetClause() (string, map[string]any) {
 		parts = append(parts, "state = @state")
 		args["state"] = *p.State
 	}
+	if p.UsedUndo != nil {
+		parts = append(parts, "used_undo = @used_undo")
+		args["used_undo"] = *p.UsedUndo
+	}
+	if p.UsedSolve != nil {
+		parts = append(parts, "used_solve = @used_solve")
+		args["used_solve"] = *p.UsedSolve
+	}
+	if p.Clicks != nil {
+		parts = append(parts,
+			"left_clicks = @left_clicks",
+			"right_clicks = @right_clicks",
+			"chord_clicks = @chord_clicks",
+			"wasted_clicks = @wasted_clicks",
+		)
+		args["left_clicks"] = p.Clicks.Left
+		args["right_clicks"] = p.Clicks.Right
+		args["chord_clicks"] = p.Clicks.Chord
+		args["wasted_clicks"] = p.Clicks.Wasted
+	}
 
 	return strings.Join(parts, ", "), args
 }
 
 func (q Queries) UpdateGameSession(
 	ctx context.Context, gameSessionId int, params UpdateGameSessionParams,
+) (*GameSession, error) {
+	return q.updateGameSession(ctx, gameSessionId, params, "")
+}
+
+/*
+ExpireGameSession saves a game whose time ran out, but only if it
+is still being played. If a move ended the game after it was read,
+the move stands, and pgx.ErrNoRows is returned.
+*/
+func (q Queries) ExpireGameSession(
+	ctx context.Context, gameSessionId int, params UpdateGameSessionParams,
+) (*GameSession, error) {
+	return q.updateGameSession(ctx, gameSessionId, params, "AND NOT dead AND NOT won")
+}
+
+func (q Queries) updateGameSession(
+	ctx context.Context, gameSessionId int, params UpdateGameSessionParams, condition string,
 ) (*GameSession, error) {
 	setClause, args := params.SetClause()
 	args["game_session_id"] = gameSessionId
 	rows, _ := q.db.Query(
 		ctx,
-		"UPDATE game_session SET "+setClause+" WHERE game_session_id = @game_session_id RETURNING *",
+		"UPDATE game_session SET "+setClause+
+			" WHERE game_session_id = @game_session_id "+condition+" RETURNING *",
 		pgx.NamedArgs(args),
 	)
 	return pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[GameSession])
 }
+
+/*
+RewriteGameStates saves every game state that isn't in the current
+format again in it, a batch at a time, and returns how many it
+rewrote. States that can't be read are left alone and reported
+together once the rest are done.
+*/
+func (q Queries) RewriteGameStates(ctx context.Context) (int, error) {
+	const batchSize = 500
+
+	type row struct {
+		GameSessionId int
+		State         []byte
+	}
+
+	var (
+		rewritten int
+		errs      []error
+		after     int
+	)
+	for {
+		rows, _ := q.db.Query(
+			ctx,
+			`SELECT game_session_id, state FROM game_session
+			WHERE substring(state from 1 for 1) <> @version
+				AND game_session_id > @after
+			ORDER BY game_session_id
+			LIMIT @limit`,
+			pgx.NamedArgs{
+				"version": []byte{mines.StateVersion},
+				"after":   after,
+				"limit":   batchSize,
+			},
+		)
+		batch, err := pgx.CollectRows(rows, pgx.RowToStructByName[row])
+		if err != nil {
+			return rewritten, err
+		}
+
+		for _, r := range batch {
+			after = r.GameSessionId
+			state, err := mines.DecodeGameState(r.State)
+			if err == nil {
+				r.State, err = state.Bytes()
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("game session %d: %w", r.GameSessionId, err))
+				continue
+			}
+			_, err = q.db.Exec(
+				ctx,
+				"UPDATE game_session SET state = $1 WHERE game_session_id = $2",
+				r.State, r.GameSessionId,
+			)
+			if err != nil {
+				return rewritten, err
+			}
+			rewritten++
+		}
+
+		if len(batch) < batchSize {
+			return rewritten, errors.Join(errs...)
+		}
+	}
+}
@@ -4,24 +4,37 @@ package repository
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/vancomm/minesweeper-server/internal/mines"
 )
 
 type Highscore struct {
-	GameSessionId string  `json:"game_session_id"`
-	Username      *string `json:"username"`
-	Width         int     `json:"width"`
-	Height        int     `json:"height"`
-	MineCount     int     `json:"mine_count"`
-	Unique        bool    `json:"unique"`
-	PlaytimeMs    float64 `json:"playtime_ms"`
+	GameSessionId string   `json:"game_session_id"`
+	Username      *string  `json:"username"`
+	Width         int      `json:"width"`
+	Height        int      `json:"height"`
+	MineCount     int      `json:"mine_count"`
+	Unique        bool     `json:"unique"`
+	Tiling        string   `json:"tiling"`
+	Wrap          bool     `json:"wrap"`
+	MinesPerCell  int      `json:"mines_per_cell"`
+	FirstClick    string   `json:"first_click"`
+	Lives         int      `json:"lives"`
+	TimeLimit     int      `json:"time_limit"`
+	Bbbv          *int     `json:"bbbv"`
+	PlaytimeMs    float64  `json:"playtime_ms"`
+	BbbvPerS      *float64 `json:"bbbv_per_s"`
+	Ioe           *float64 `json:"ioe"`
+	Correctness   *float64 `json:"correctness"`
 }
 
 type HighscoreFilter struct {
 	Username   *string
 	GameParams *mines.GameParams
+	MinBbbv    *int
+	MaxBbbv    *int
 }
 
 func (f HighscoreFilter) WhereClause() (string, pgx.NamedArgs) {
@@ -38,11 +51,35 @@ func (f HighscoreFilter) WhereClause() (string, pgx.NamedArgs) {
 			"height = @height",
 			"mine_count = @mineCount",
 			`"unique" = @unique`,
+			"tiling = @tiling",
+			"wrap = @wrap",
+			"mines_per_cell = @minesPerCell",
+			"first_click = @firstClick",
+			"lives = @lives",
+			"time_limit = @timeLimit",
 		)
 		args["width"] = f.GameParams.Width
 		args["height"] = f.GameParams.Height
 		args["mineCount"] = f.GameParams.MineCount
 		args["unique"] = f.GameParams.Unique
+		args["tiling"] = f.GameParams.Tiling.String()
+		args["wrap"] = f.GameParams.Wrap
+		args["minesPerCell"] = f.GameParams.PerCell()
+		args["firstClick"] = f.GameParams.FirstClick.String()
+		args["lives"] = f.GameParams.Lives
+		args["timeLimit"] = f.GameParams.TimeLimit
+		if f.GameParams.MaxGrade != mines.GradeAny {
+			clauses = append(clauses, "grade <= @maxGrade")
+			args["maxGrade"] = int(f.GameParams.MaxGrade)
+		}
+	}
+	if f.MinBbbv != nil {
+		clauses = append(clauses, "bbbv >= @minBbbv")
+		args["minBbbv"] = *f.MinBbbv
+	}
+	if f.MaxBbbv != nil {
+		clauses = append(clauses, "bbbv <= @maxBbbv")
+		args["maxBbbv"] = *f.MaxBbbv
 	}
 	return strings.Join(clauses, " AND "), args
 
@@ -59,15 +96,33 @@ func (q Queries) GetHighscores(
 		height,
 		mine_count,
 		"unique",
+		tiling,
+		wrap,
+		mines_per_cell,
+		first_click,
+		lives,
+		time_limit,
+		bbbv,
 		(
 			extract('epoch' from ended_at) -
 			extract('epoch' from started_at)
-		) * 1000 playtime_ms
+		) * 1000 playtime_ms,
+		bbbv / NULLIF(
+			extract('epoch' from ended_at) -
+			extract('epoch' from started_at), 0
+		)::float8 bbbv_per_s,
+		bbbv / NULLIF(left_clicks + right_clicks + chord_clicks, 0)::float8 ioe,
+		(left_clicks + right_clicks + chord_clicks - wasted_clicks) /
+			NULLIF(left_clicks + right_clicks + chord_clicks, 0)::float8 correctness
 	FROM game_session
 		LEFT OUTER JOIN player using (player_id)
 	WHERE 
 		won = true 
 		AND dead = false 
+		AND used_undo = false
+		AND used_solve = false
+		AND custom_layout = false
+		AND daily_date IS NULL
 		AND ended_at IS NOT NULL
 	`
 
@@ -84,3 +139,67 @@ func (q Queries) GetHighscores(
 	}
 	return pgx.CollectRows(rows, pgx.RowToStructByName[Highscore])
 }
+
+/*
+Only a player's first attempt at a daily board is ranked, and only
+if they were logged in when they made it.
+*/
+func (q Queries) GetDailyHighscores(
+	ctx context.Context, date time.Time, preset string,
+) ([]Highscore, error) {
+	query := `
+	WITH first_attempt AS (
+		SELECT DISTINCT ON (player_id) *
+		FROM game_session
+		WHERE 
+			daily_date = @daily_date
+			AND daily_preset = @daily_preset
+			AND player_id IS NOT NULL
+		ORDER BY player_id, started_at
+	)
+	SELECT 
+		game_session_id,
+		username,
+		width,
+		height,
+		mine_count,
+		"unique",
+		tiling,
+		wrap,
+		mines_per_cell,
+		first_click,
+		lives,
+		time_limit,
+		bbbv,
+		(
+			extract('epoch' from ended_at) -
+			extract('epoch' from started_at)
+		) * 1000 playtime_ms,
+		bbbv / NULLIF(
+			extract('epoch' from ended_at) -
+			extract('epoch' from started_at), 0
+		)::float8 bbbv_per_s,
+		bbbv / NULLIF(left_clicks + right_clicks + chord_clicks, 0)::float8 ioe,
+		(left_clicks + right_clicks + chord_clicks - wasted_clicks) /
+			NULLIF(left_clicks + right_clicks + chord_clicks, 0)::float8 correctness
+	FROM first_attempt
+		JOIN player using (player_id)
+	WHERE 
+		won = true 
+		AND dead = false 
+		AND used_undo = false
+		AND used_solve = false
+		AND ended_at IS NOT NULL
+	ORDER BY playtime_ms;
+	`
+
+	args := pgx.NamedArgs{
+		"daily_date":   date,
+		"daily_preset": preset,
+	}
+	rows, err := q.db.Query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Highscore])
+}